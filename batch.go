@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// input represents an input file expanded from the arguments.
+type input struct {
+	// path is the path to read.
+	path string
+	// rel is the relative path preserved in --output-dir.
+	rel string
+	// err is the error in expanding the argument (nil if none), reported in place of the file.
+	err error
+}
+
+// localPath returns the path itself if it is within the current directory, or its base name otherwise.
+func localPath(path string) string {
+	if filepath.IsLocal(path) {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Base(path)
+}
+
+// isGlob reports whether the argument is a glob pattern rather than an existing file.
+func isGlob(arg string) bool {
+	if !strings.ContainsAny(arg, "*?[") {
+		return false
+	}
+
+	_, err := os.Lstat(arg)
+	return err != nil
+}
+
+// matches reports whether the base name of the file matches the include patterns (if any) and not the exclude patterns.
+func matches(opts *options, path string) (bool, error) {
+	name := filepath.Base(path)
+
+	for _, pattern := range opts.Exclude {
+		matched, err := filepath.Match(pattern, name)
+		if err != nil || matched {
+			return false, err
+		}
+	}
+
+	if len(opts.Include) == 0 {
+		return true, nil
+	}
+
+	for _, pattern := range opts.Include {
+		matched, err := filepath.Match(pattern, name)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+
+	return false, nil
+}
+
+// walk returns the regular files in the directory in lexical order, which match the include and exclude patterns.
+func walk(opts *options, dir string) []input {
+	var inputs []input
+
+	root := localPath(dir)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			inputs = append(inputs, input{path: path, err: err})
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		matched, err := matches(opts, path)
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+
+			inputs = append(inputs, input{path: path, rel: filepath.Join(root, rel)})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		inputs = append(inputs, input{path: dir, err: err})
+	}
+
+	return inputs
+}
+
+// expand expands the arguments into input files.
+// Glob patterns are expanded in lexical order, and directories are walked recursively with -r.
+// Errors are kept in place of the files, so that they are reported in order without aborting the others.
+func expand(opts *options, args []string) []input {
+	var inputs []input
+
+	for _, arg := range args {
+		paths := []string{arg}
+
+		if arg != "-" && isGlob(arg) {
+			matched, err := filepath.Glob(arg)
+			if err == nil && len(matched) == 0 {
+				err = errors.New("no files match the pattern")
+			}
+			if err != nil {
+				inputs = append(inputs, input{path: arg, err: err})
+				continue
+			}
+
+			sort.Strings(matched)
+			paths = matched
+		}
+
+		for _, path := range paths {
+			if path == "-" {
+				inputs = append(inputs, input{path: path})
+				continue
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				// reported by load
+				inputs = append(inputs, input{path: path, rel: localPath(path)})
+				continue
+			}
+
+			if !info.IsDir() {
+				inputs = append(inputs, input{path: path, rel: localPath(path)})
+				continue
+			}
+
+			if !opts.Recursive {
+				inputs = append(inputs, input{path: path, err: errors.New("is a directory (use -r to read recursively)")})
+				continue
+			}
+
+			inputs = append(inputs, walk(opts, path)...)
+		}
+	}
+
+	return inputs
+}
+
+// reportError reports the error of an input file to the standard error.
+func reportError(path string, err error) {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", appname, err)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %s: %v\n", appname, path, err)
+}
+
+// result is the result of processing an input file by a worker.
+type result struct {
+	// out is the output buffered until the outputs of the previous files are written.
+	out bytes.Buffer
+	err error
+
+	// done is closed when the file has been processed.
+	done chan struct{}
+}
+
+// processBatch processes each input file on its own screen with opts.Jobs workers,
+// and writes the results to the Writer in order of the input files (unless written to their own files).
+// Errors are reported for each file without aborting the others, and processBatch reports whether all the files succeeded.
+// The output of a file up to an error is written regardless of the number of workers,
+// and at most opts.Jobs files are processed or buffered ahead of the output.
+func processBatch(opts *options, inputs []input, w io.Writer, dumper *clipboardDumper, extractor *imageExtractor) bool {
+	sw := &separatedWriter{w: w}
+	ownFiles := opts.InPlace != nil || opts.OutputDir != ""
+
+	// processOne processes an input file, writing the result to the Writer unless to its own file.
+	processOne := func(in input, out io.Writer) error {
+		if in.err != nil {
+			return in.err
+		}
+
+		if ownFiles {
+			return processTo(opts, in.path, destination(opts, in), dumper, extractor)
+		}
+
+		filter := newFilter(opts, dumper, extractor)
+		return process(filter, opts, []string{in.path}, out)
+	}
+
+	ok := true
+
+	if opts.Jobs <= 1 {
+		// write the output directly, so that --stream outputs lines as they become final
+		for _, in := range inputs {
+			if in.err == nil && !ownFiles {
+				if err := sw.begin(in.path, opts.Headers); err != nil {
+					reportError(in.path, err)
+					return false
+				}
+			}
+
+			if err := processOne(in, sw); err != nil {
+				reportError(in.path, err)
+				ok = false
+			}
+		}
+
+		return ok
+	}
+
+	results := make([]*result, len(inputs))
+	indices := make(chan int)
+
+	// slots limits the files in flight (processed or buffered but not written yet) to opts.Jobs,
+	// so that the outputs are not buffered for all the files behind a slow one
+	slots := make(chan struct{}, opts.Jobs)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for i := range results {
+		results[i] = &result{done: make(chan struct{})}
+	}
+
+	for j := 0; j < opts.Jobs; j++ {
+		go func() {
+			for i := range indices {
+				results[i].err = processOne(inputs[i], &results[i].out)
+				close(results[i].done)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indices)
+
+		for i := range inputs {
+			select {
+			case slots <- struct{}{}:
+				indices <- i
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	for i, r := range results {
+		<-r.done
+
+		// the output up to an error is written as in the sequential processing
+		if inputs[i].err == nil && !ownFiles {
+			if err := sw.begin(inputs[i].path, opts.Headers); err != nil {
+				reportError(inputs[i].path, err)
+				return false
+			}
+
+			if _, err := r.out.WriteTo(sw); err != nil {
+				reportError(inputs[i].path, err)
+				return false
+			}
+		}
+
+		if r.err != nil {
+			reportError(inputs[i].path, r.err)
+			ok = false
+		}
+
+		results[i] = nil
+		<-slots
+	}
+
+	return ok
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_matches(t *testing.T) {
+	tests := []struct {
+		include  []string
+		exclude  []string
+		path     string
+		expected bool
+	}{
+		{path: "logs/build.log", expected: true},
+		{include: []string{"*.log"}, path: "logs/build.log", expected: true},
+		{include: []string{"*.txt", "*.log"}, path: "logs/build.log", expected: true},
+		{include: []string{"*.txt"}, path: "logs/build.log", expected: false},
+		{exclude: []string{"build.*"}, path: "logs/build.log", expected: false},
+		{include: []string{"*.log"}, exclude: []string{"build.*"}, path: "logs/build.log", expected: false},
+		{include: []string{"logs"}, path: "logs/build.log", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("include=%q,exclude=%q,path=%q", tt.include, tt.exclude, tt.path), func(t *testing.T) {
+			opts := &options{Include: tt.include, Exclude: tt.exclude}
+
+			actual, err := matches(opts, tt.path)
+			if err != nil {
+				t.Fatalf("matches() should not return error, got %v", err)
+			}
+
+			if actual != tt.expected {
+				t.Errorf("matches() should return %t, got %t", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// expanded is an input compared in tests, whose error is only checked for existence.
+type expanded struct {
+	Path string
+	Rel  string
+	Err  bool
+}
+
+func Test_expand(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"logs/a.log", "logs/b.txt", "logs/sub/c.log", "top.log"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	abs := filepath.Join(dir, "logs")
+
+	tests := []struct {
+		opts     options
+		args     []string
+		expected []expanded
+	}{
+		{
+			args:     []string{"-", "top.log", "missing.log"},
+			expected: []expanded{{Path: "-"}, {Path: "top.log", Rel: "top.log"}, {Path: "missing.log", Rel: "missing.log"}},
+		},
+		{
+			args:     []string{"logs"},
+			expected: []expanded{{Path: "logs", Err: true}},
+		},
+		{
+			opts: options{Recursive: true},
+			args: []string{"logs"},
+			expected: []expanded{
+				{Path: "logs/a.log", Rel: "logs/a.log"},
+				{Path: "logs/b.txt", Rel: "logs/b.txt"},
+				{Path: "logs/sub/c.log", Rel: "logs/sub/c.log"},
+			},
+		},
+		{
+			opts: options{Recursive: true, Include: []string{"*.log"}, Exclude: []string{"c.*"}},
+			args: []string{"logs", "top.log"},
+			expected: []expanded{
+				{Path: "logs/a.log", Rel: "logs/a.log"},
+				{Path: "top.log", Rel: "top.log"},
+			},
+		},
+		{
+			opts: options{Recursive: true},
+			args: []string{abs},
+			expected: []expanded{
+				{Path: filepath.Join(abs, "a.log"), Rel: "logs/a.log"},
+				{Path: filepath.Join(abs, "b.txt"), Rel: "logs/b.txt"},
+				{Path: filepath.Join(abs, "sub/c.log"), Rel: "logs/sub/c.log"},
+			},
+		},
+		{
+			args: []string{"*/*.log", "*.none", filepath.Join(abs, "*.txt")},
+			expected: []expanded{
+				{Path: "logs/a.log", Rel: "logs/a.log"},
+				{Path: "*.none", Err: true},
+				{Path: filepath.Join(abs, "b.txt"), Rel: "b.txt"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("opts=%+v,args=%q", tt.opts, tt.args), func(t *testing.T) {
+			var actual []expanded
+			for _, in := range expand(&tt.opts, tt.args) {
+				actual = append(actual, expanded{Path: in.path, Rel: in.rel, Err: in.err != nil})
+			}
+
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Errorf("expand() differs from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+// captureStderr returns what the function writes to the standard error.
+func captureStderr(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	done := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- string(b)
+	}()
+
+	fn()
+	w.Close()
+
+	return <-done
+}
+
+func Test_processBatch(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.log": "a\n", "b.log": "b", "long.log": "ok\ntoo long", "c.log": "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	inputs := []input{
+		{path: path("a.log")},
+		{path: path("missing.log")},
+		{path: path("b.log")},
+		{path: path("long.log")},
+		{path: path("logs"), err: errors.New("is a directory (use -r to read recursively)")},
+		{path: path("c.log")},
+	}
+
+	expectedOut := strings.Join([]string{
+		"==> " + path("a.log") + " <==",
+		"a",
+		"",
+		"==> " + path("missing.log") + " <==",
+		"",
+		"==> " + path("b.log") + " <==",
+		"b",
+		"",
+		"==> " + path("long.log") + " <==",
+		"",
+		"==> " + path("c.log") + " <==",
+		"c",
+	}, "\n")
+
+	expectedErr := strings.Join([]string{
+		"escapefilter: open " + path("missing.log") + ": no such file or directory",
+		"escapefilter: " + path("long.log") + ": cols limit (3) exceeded at row 2, col 4",
+		"escapefilter: " + path("logs") + ": is a directory (use -r to read recursively)",
+		"",
+	}, "\n")
+
+	for _, jobs := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("jobs=%d", jobs), func(t *testing.T) {
+			opts := &options{Format: "text", Links: "plain", Headers: true, Jobs: jobs, MaxCols: 3, StrictLimits: true}
+
+			var buf bytes.Buffer
+			var ok bool
+			stderr := captureStderr(t, func() {
+				ok = processBatch(opts, inputs, &buf, nil, nil)
+			})
+
+			if ok {
+				t.Errorf("processBatch() should return false")
+			}
+
+			if actual := buf.String(); actual != expectedOut {
+				t.Errorf("processBatch() should write %q, got %q", expectedOut, actual)
+			}
+
+			if stderr != expectedErr {
+				t.Errorf("processBatch() should report %q, got %q", expectedErr, stderr)
+			}
+		})
+	}
+}
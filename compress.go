@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"path/filepath"
+)
+
+// decompress returns the Reader decompressing the input if it starts with the magic bytes of gzip, bzip2 or zlib,
+// or the Reader reading the input as it is otherwise.
+// The Reader should be closed, which doesn't close the input.
+func decompress(rd io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(rd)
+
+	// an error means the input is too short to be compressed
+	magic, _ := br.Peek(10)
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case isBzip2(magic):
+		return io.NopCloser(bzip2.NewReader(br)), nil
+	case isZlib(magic):
+		return zlib.NewReader(br)
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// isBzip2 reports whether the bytes start with the bzip2 header ("BZh" and the block size)
+// followed by the magic of a block or the end of the stream, which is not likely in text.
+func isBzip2(magic []byte) bool {
+	if len(magic) < 10 || !bytes.HasPrefix(magic, []byte("BZh")) || magic[3] < '1' || '9' < magic[3] {
+		return false
+	}
+
+	block := []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	eos := []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+
+	return bytes.Equal(magic[4:10], block) || bytes.Equal(magic[4:10], eos)
+}
+
+// isZlib reports whether the bytes start with the zlib header of deflate with 32K window.
+// The header of the fast levels ("x^") is not detected, since it is likely in text.
+func isZlib(magic []byte) bool {
+	if len(magic) < 2 || magic[0] != 0x78 {
+		return false
+	}
+
+	switch magic[1] {
+	case 0x01, 0x9c, 0xda:
+		return true
+	default:
+		return false
+	}
+}
+
+// compression returns the compression format of the output file.
+// In "auto", the format is determined by the extension of the file name.
+// Since bzip2 can only be read, compression returns an error for ".bz2" instead of writing it in another format.
+func compression(format string, path string) (string, error) {
+	if format != "auto" {
+		return format, nil
+	}
+
+	switch filepath.Ext(path) {
+	case ".gz":
+		return "gzip", nil
+	case ".zz", ".zlib":
+		return "zlib", nil
+	case ".bz2":
+		return "", errors.New("bzip2 compression is not supported (use --compress to write in another format)")
+	default:
+		return "none", nil
+	}
+}
+
+// compressor returns the WriteCloser compressing the output to the Writer in the format (nil if "none").
+func compressor(w io.Writer, format string) io.WriteCloser {
+	switch format {
+	case "gzip":
+		return gzip.NewWriter(w)
+	case "zlib":
+		return zlib.NewWriter(w)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func Test_decompress(t *testing.T) {
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("Hello"))
+	gw.Close()
+
+	var zz bytes.Buffer
+	zw := zlib.NewWriter(&zz)
+	zw.Write([]byte("Hello"))
+	zw.Close()
+
+	bz2 := "BZh91AY&SY\x1aTd\x92\x00\x00\x00\x05\x00\x00@\x02\x04\xa0\x00!\x9ah3M\x133\x8b\xb9\"\x9c(H\r*2I\x00"
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Hello", expected: "Hello"},
+		{input: "", expected: ""},
+		{input: gz.String(), expected: "Hello"},
+		{input: zz.String(), expected: "Hello"},
+		{input: bz2, expected: "Hello"},
+		{input: "BZh9 text", expected: "BZh9 text"},
+		{input: "x^2", expected: "x^2"},
+		{input: "x", expected: "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("input=%q", tt.input), func(t *testing.T) {
+			rd, err := decompress(bytes.NewReader([]byte(tt.input)))
+			if err != nil {
+				t.Fatalf("decompress() should not return error, got %v", err)
+			}
+			defer rd.Close()
+
+			actual, err := io.ReadAll(rd)
+			if err != nil {
+				t.Fatalf("ReadAll() should not return error, got %v", err)
+			}
+
+			if string(actual) != tt.expected {
+				t.Errorf("decompress() should read %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_compression(t *testing.T) {
+	tests := []struct {
+		format   string
+		path     string
+		expected string
+		isError  bool
+	}{
+		{format: "auto", path: "build.log", expected: "none", isError: false},
+		{format: "auto", path: "build.log.gz", expected: "gzip", isError: false},
+		{format: "auto", path: "build.log.zz", expected: "zlib", isError: false},
+		{format: "auto", path: "build.log.zlib", expected: "zlib", isError: false},
+		{format: "auto", path: "build.log.bz2", expected: "", isError: true},
+		{format: "none", path: "build.log.gz", expected: "none", isError: false},
+		{format: "none", path: "build.log.bz2", expected: "none", isError: false},
+		{format: "gzip", path: "build.log.bz2", expected: "gzip", isError: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("format=%q,path=%q", tt.format, tt.path), func(t *testing.T) {
+			format, err := compression(tt.format, tt.path)
+
+			if tt.isError {
+				if err == nil {
+					t.Errorf("compression() should return error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("compression() should not return error, got %v", err)
+			}
+
+			if format != tt.expected {
+				t.Errorf("compression() should return %q, got %q", tt.expected, format)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/mattn/go-runewidth"
+	"strings"
+)
+
+// wideTail is the cell occupied by the right half of a wide character.
+const wideTail rune = -1
+
+// cells stores characters in a line by column, so that a character can be put at any column in O(1).
+// A wide character occupies two cells: the character itself followed by wideTail.
+// The width of the line is the number of the cells, and an empty line is nil.
+type cells []rune
+
+// newCells returns the cells of the string, which has no control characters.
+func newCells(str string) cells {
+	var c cells
+
+	col := 1
+	for _, r := range str {
+		c, col = c.put(col, r)
+	}
+
+	return c
+}
+
+// String returns the characters in the cells.
+func (c cells) String() string {
+	var sb strings.Builder
+
+	for _, r := range c {
+		if r != wideTail {
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// width returns the width of the line.
+func (c cells) width() int {
+	return len(c)
+}
+
+// put puts a rune at the column (1-based), and returns the new cells and the column after the rune.
+// The line is padded with spaces if the column is beyond the end of the line,
+// and the other half of a wide character partially overwritten is replaced with a space.
+// Zero-width runes are ignored.
+func (c cells) put(col int, r rune) (cells, int) {
+	if col <= 0 {
+		panic(fmt.Sprintf("col must be >= 1, got %d", col))
+	}
+
+	w := runewidth.RuneWidth(r)
+	if w <= 0 {
+		return c, col
+	}
+
+	i := col - 1
+	for len(c) < i+w {
+		c = append(c, ' ')
+	}
+
+	if c[i] == wideTail && i > 0 {
+		c[i-1] = ' '
+	}
+
+	if i+w < len(c) && c[i+w] == wideTail {
+		c[i+w] = ' '
+	}
+
+	c[i] = r
+	if w == 2 {
+		c[i+1] = wideTail
+	}
+
+	return c, col + w
+}
+
+// truncate returns the cells up to the width, without a wide character crossing the end.
+func (c cells) truncate(w int) cells {
+	if len(c) <= w {
+		return c
+	}
+
+	if w > 0 && c[w] == wideTail {
+		w--
+	}
+
+	if w <= 0 {
+		return nil
+	}
+
+	return c[:w]
+}
+
+// truncateLeft returns the cells after the width, whose wide character crossing the beginning is replaced with a space.
+func (c cells) truncateLeft(w int) cells {
+	if len(c) <= w {
+		return nil
+	}
+
+	if w <= 0 {
+		return c
+	}
+
+	rest := append(cells{}, c[w:]...)
+	if rest[0] == wideTail {
+		rest[0] = ' '
+	}
+
+	return rest
+}
+
+// eraseBefore erases characters up to the column (1-based) with spaces, keeping the width.
+// The line becomes empty if all the characters are erased.
+func (c cells) eraseBefore(col int) cells {
+	if len(c) <= col {
+		return nil
+	}
+
+	for i := 0; i < col; i++ {
+		c[i] = ' '
+	}
+
+	if c[col] == wideTail {
+		c[col] = ' '
+	}
+
+	return c
+}
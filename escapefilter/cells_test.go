@@ -0,0 +1,231 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/andreyvit/diff"
+	"strings"
+	"testing"
+)
+
+// newLines returns the lines of cells from the strings.
+func newLines(strs ...string) []cells {
+	lines := make([]cells, len(strs))
+	for i, str := range strs {
+		lines[i] = newCells(str)
+	}
+
+	return lines
+}
+
+func Test_cells_put(t *testing.T) {
+	tests := []struct {
+		line    string
+		col     int
+		r       rune
+		newLine string
+		newCol  int
+	}{
+		{
+			line:    "Hello World",
+			col:     12,
+			r:       'X',
+			newLine: "Hello WorldX",
+			newCol:  13,
+		},
+		{
+			line:    "Hello World",
+			col:     5,
+			r:       'X',
+			newLine: "HellX World",
+			newCol:  6,
+		},
+		{
+			line:    "Hello World",
+			col:     15,
+			r:       'X',
+			newLine: "Hello World   X",
+			newCol:  16,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     18,
+			r:       'あ',
+			newLine: "こんにちはABC世界あ",
+			newCol:  20,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     5,
+			r:       'あ',
+			newLine: "こんあちはABC世界",
+			newCol:  7,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     6,
+			r:       'あ',
+			newLine: "こん あ はABC世界",
+			newCol:  8,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     11,
+			r:       'あ',
+			newLine: "こんにちはあC世界",
+			newCol:  13,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     13,
+			r:       'あ',
+			newLine: "こんにちはABあ 界",
+			newCol:  15,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     14,
+			r:       'あ',
+			newLine: "こんにちはABCあ界",
+			newCol:  16,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     20,
+			r:       'あ',
+			newLine: "こんにちはABC世界  あ",
+			newCol:  22,
+		},
+		{
+			line:    "こんにちはABC世界",
+			col:     18,
+			r:       '\u0000',
+			newLine: "こんにちはABC世界",
+			newCol:  18,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("line=%s,col=%d,r=%U", tt.line, tt.col, tt.r), func(t *testing.T) {
+			newLine, newCol := newCells(tt.line).put(tt.col, tt.r)
+
+			if newLine.String() != tt.newLine {
+				t.Errorf("newLine differs from expected\n%v", diff.LineDiff(tt.newLine, newLine.String()))
+			}
+
+			if newCol != tt.newCol {
+				t.Errorf("newCol should be %d, got %d", tt.newCol, newCol)
+			}
+		})
+	}
+}
+
+func Test_cells_truncate(t *testing.T) {
+	tests := []struct {
+		line     string
+		w        int
+		expected string
+	}{
+		{line: "Hello World", w: 5, expected: "Hello"},
+		{line: "Hello World", w: 11, expected: "Hello World"},
+		{line: "Hello World", w: 20, expected: "Hello World"},
+		{line: "Hello World", w: 0, expected: ""},
+		{line: "こんにちは", w: 4, expected: "こん"},
+		{line: "こんにちは", w: 5, expected: "こん"},
+		{line: "こんにちは", w: 1, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("line=%s,w=%d", tt.line, tt.w), func(t *testing.T) {
+			if actual := newCells(tt.line).truncate(tt.w); actual.String() != tt.expected {
+				t.Errorf("truncate() should return %q, got %q", tt.expected, actual.String())
+			}
+		})
+	}
+}
+
+func Test_cells_truncateLeft(t *testing.T) {
+	tests := []struct {
+		line     string
+		w        int
+		expected string
+	}{
+		{line: "Hello World", w: 6, expected: "World"},
+		{line: "Hello World", w: 0, expected: "Hello World"},
+		{line: "Hello World", w: 11, expected: ""},
+		{line: "こんにちは", w: 4, expected: "にちは"},
+		{line: "こんにちは", w: 5, expected: " ちは"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("line=%s,w=%d", tt.line, tt.w), func(t *testing.T) {
+			if actual := newCells(tt.line).truncateLeft(tt.w); actual.String() != tt.expected {
+				t.Errorf("truncateLeft() should return %q, got %q", tt.expected, actual.String())
+			}
+		})
+	}
+}
+
+func Test_cells_eraseBefore(t *testing.T) {
+	tests := []struct {
+		line     string
+		col      int
+		expected string
+	}{
+		{line: "Hello World", col: 5, expected: "      World"},
+		{line: "Hello World", col: 11, expected: ""},
+		{line: "こんにちは", col: 4, expected: "    にちは"},
+		{line: "こんにちは", col: 5, expected: "      ちは"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("line=%s,col=%d", tt.line, tt.col), func(t *testing.T) {
+			if actual := newCells(tt.line).eraseBefore(tt.col); actual.String() != tt.expected {
+				t.Errorf("eraseBefore() should return %q, got %q", tt.expected, actual.String())
+			}
+		})
+	}
+}
+
+// benchmarkLoad measures loading the source.
+func benchmarkLoad(b *testing.B, source string) {
+	b.SetBytes(int64(len(source)))
+
+	for i := 0; i < b.N; i++ {
+		if err := New().Load(strings.NewReader(source)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Each benchmark should scale linearly with n, i.e. the throughput should not depend on n.
+
+func Benchmark_EscapeFilter_Load_LongLine(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			benchmarkLoad(b, strings.Repeat("あa", n/2)+"\r"+strings.Repeat("b", n))
+		})
+	}
+}
+
+func Benchmark_EscapeFilter_Load_Progress(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			var sb strings.Builder
+			sb.WriteString(strings.Repeat("x", n))
+
+			for i := 0; i < n/10; i++ {
+				fmt.Fprintf(&sb, "\r[%-10s] %3d%%\x1b[K", strings.Repeat("#", i%11), i%101)
+			}
+
+			benchmarkLoad(b, sb.String())
+		})
+	}
+}
+
+func Benchmark_EscapeFilter_Load_ManyLines(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			benchmarkLoad(b, strings.Repeat("line\x1b[2K\rprogress\n", n))
+		})
+	}
+}
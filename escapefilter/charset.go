@@ -0,0 +1,91 @@
+package escapefilter
+
+// Charset represents a graphic character set which can be designated to G0-G3.
+type Charset int
+
+// Supported character sets
+const (
+	CharsetASCII Charset = iota
+	CharsetUK
+	CharsetDECSpecialGraphics
+	CharsetLatin1Supplement
+)
+
+// decSpecialGraphics maps characters in 0x5F-0x7E to DEC Special Graphics.
+var decSpecialGraphics = map[rune]rune{
+	'_': '\u0020', // blank
+	'`': '\u25C6', // ◆
+	'a': '\u2592', // ▒
+	'b': '\u2409', // ␉
+	'c': '\u240C', // ␌
+	'd': '\u240D', // ␍
+	'e': '\u240A', // ␊
+	'f': '\u00B0', // °
+	'g': '\u00B1', // ±
+	'h': '\u2424', // ␤
+	'i': '\u240B', // ␋
+	'j': '\u2518', // ┘
+	'k': '\u2510', // ┐
+	'l': '\u250C', // ┌
+	'm': '\u2514', // └
+	'n': '\u253C', // ┼
+	'o': '\u23BA', // ⎺
+	'p': '\u23BB', // ⎻
+	'q': '\u2500', // ─
+	'r': '\u23BC', // ⎼
+	's': '\u23BD', // ⎽
+	't': '\u251C', // ├
+	'u': '\u2524', // ┤
+	'v': '\u2534', // ┴
+	'w': '\u252C', // ┬
+	'x': '\u2502', // │
+	'y': '\u2264', // ≤
+	'z': '\u2265', // ≥
+	'{': '\u03C0', // π
+	'|': '\u2260', // ≠
+	'}': '\u00A3', // £
+	'~': '\u00B7', // ·
+}
+
+// parseCharset returns the charset designated by the final character of SCS,
+// which has different meanings for 94-character sets and 96-character sets.
+// Unsupported character sets are treated as ASCII.
+func parseCharset(final string, is96 bool) Charset {
+	if is96 {
+		if final == "A" {
+			return CharsetLatin1Supplement
+		}
+
+		return CharsetASCII
+	}
+
+	switch final {
+	case "A":
+		return CharsetUK
+	case "0":
+		return CharsetDECSpecialGraphics
+	default:
+		return CharsetASCII
+	}
+}
+
+// translate maps the rune to the corresponding character in the charset.
+func (c Charset) translate(r rune) rune {
+	switch c {
+	case CharsetUK:
+		if r == '#' {
+			return '\u00A3' // £
+		}
+	case CharsetDECSpecialGraphics:
+		if t, ok := decSpecialGraphics[r]; ok {
+			return t
+		}
+	case CharsetLatin1Supplement:
+		// the upper half of ISO 8859-1 (0xA0-0xFF) in GL
+		if r >= 0x20 && r <= 0x7F {
+			return r + 0x80
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,58 @@
+package escapefilter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_parseCharset(t *testing.T) {
+	tests := []struct {
+		final    string
+		is96     bool
+		expected Charset
+	}{
+		{final: "B", is96: false, expected: CharsetASCII},
+		{final: "A", is96: false, expected: CharsetUK},
+		{final: "0", is96: false, expected: CharsetDECSpecialGraphics},
+		{final: "K", is96: false, expected: CharsetASCII},
+		{final: "A", is96: true, expected: CharsetLatin1Supplement},
+		{final: "0", is96: true, expected: CharsetASCII},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("final=%q,is96=%v", tt.final, tt.is96), func(t *testing.T) {
+			if cs := parseCharset(tt.final, tt.is96); cs != tt.expected {
+				t.Errorf("parseCharset() should return %d, got %d", tt.expected, cs)
+			}
+		})
+	}
+}
+
+func Test_Charset_translate(t *testing.T) {
+	tests := []struct {
+		cs       Charset
+		r        rune
+		expected rune
+	}{
+		{cs: CharsetASCII, r: 'q', expected: 'q'},
+		{cs: CharsetASCII, r: '#', expected: '#'},
+		{cs: CharsetUK, r: '#', expected: '£'},
+		{cs: CharsetUK, r: 'q', expected: 'q'},
+		{cs: CharsetDECSpecialGraphics, r: 'q', expected: '─'},
+		{cs: CharsetDECSpecialGraphics, r: 'x', expected: '│'},
+		{cs: CharsetDECSpecialGraphics, r: 'l', expected: '┌'},
+		{cs: CharsetDECSpecialGraphics, r: 'A', expected: 'A'},
+		{cs: CharsetDECSpecialGraphics, r: 'あ', expected: 'あ'},
+		{cs: CharsetLatin1Supplement, r: 'i', expected: 'é'},
+		{cs: CharsetLatin1Supplement, r: ' ', expected: '\u00A0'},
+		{cs: CharsetLatin1Supplement, r: 'あ', expected: 'あ'},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("cs=%d,r=%q", tt.cs, tt.r), func(t *testing.T) {
+			if r := tt.cs.translate(tt.r); r != tt.expected {
+				t.Errorf("translate() should return %q, got %q", tt.expected, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package escapefilter
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// DefaultClipboardLimit is the default maximum size of a clipboard payload in bytes.
+const DefaultClipboardLimit = 1 << 20
+
+// Clipboard represents a payload copied into the clipboard by OSC 52.
+type Clipboard struct {
+	Position
+
+	// Selection is the target selection, e.g. "c" (clipboard), "p" (primary).
+	// Selection is "s0" if not specified, which is the default of xterm.
+	Selection string
+	// Data is the decoded payload.
+	Data []byte
+}
+
+// ClipboardHandler is called with a clipboard payload.
+// An error returned by ClipboardHandler stops loading.
+type ClipboardHandler func(c Clipboard) error
+
+// parseClipboard parses the parameter of OSC 52, which is <selection> ; <base64 data>.
+// parseClipboard returns nil for a query ("?").
+// Payloads larger than limit bytes are rejected before decoding.
+func parseClipboard(param string, limit int) (*Clipboard, error) {
+	selection, data, ok := strings.Cut(param, ";")
+	if !ok {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	if data == "?" {
+		return nil, nil
+	}
+
+	if selection == "" {
+		selection = "s0"
+	}
+
+	if base64.StdEncoding.DecodedLen(len(data)) > limit+2 {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(decoded) > limit {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	return &Clipboard{Selection: selection, Data: decoded}, nil
+}
+
+// clipboardSelectionLimit is the maximum length of the selection of OSC 52 accepted without the data.
+const clipboardSelectionLimit = 16
+
+// clipboardPayloadLimit returns the maximum length of the parameter of OSC 52 with a payload up to limit bytes.
+func clipboardPayloadLimit(limit int) int {
+	return clipboardSelectionLimit + len(";") + base64.StdEncoding.EncodedLen(limit)
+}
+
+// SetClipboardHandler sets the function called with each clipboard payload.
+// Clipboard payloads are never output as text, whether the handler is set or not.
+func (f *EscapeFilter) SetClipboardHandler(h ClipboardHandler) {
+	f.clipboardHandler = h
+}
+
+// SetClipboardLimit sets the maximum size of a clipboard payload in bytes.
+// Larger payloads are discarded without calling the handler.
+func (f *EscapeFilter) SetClipboardLimit(limit int) {
+	f.clipboardLimit = limit
+}
@@ -0,0 +1,123 @@
+package escapefilter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_parseClipboard(t *testing.T) {
+	tests := []struct {
+		param   string
+		limit   int
+		c       *Clipboard
+		isError bool
+	}{
+		{param: "c;SGVsbG8=", limit: 16, c: &Clipboard{Selection: "c", Data: []byte("Hello")}},
+		{param: "pc;SGVsbG8gV29ybGQ=", limit: 16, c: &Clipboard{Selection: "pc", Data: []byte("Hello World")}},
+		{param: ";SGVsbG8=", limit: 16, c: &Clipboard{Selection: "s0", Data: []byte("Hello")}},
+		{param: "c;", limit: 16, c: &Clipboard{Selection: "c", Data: []byte{}}},
+		{param: "c;?", limit: 16, c: nil},
+		{param: "c;SGVsbG8=", limit: 5, c: &Clipboard{Selection: "c", Data: []byte("Hello")}},
+		{param: "c;SGVsbG8=", limit: 4, c: nil, isError: true},
+		{param: "c;SGVsbG8gV29ybGQ=", limit: 4, c: nil, isError: true},
+		{param: "c;!!!!", limit: 16, c: nil, isError: true},
+		{param: "SGVsbG8=", limit: 16, c: nil, isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q,limit=%d", tt.param, tt.limit), func(t *testing.T) {
+			c, err := parseClipboard(tt.param, tt.limit)
+
+			if diff := cmp.Diff(tt.c, c); diff != "" {
+				t.Errorf("parseClipboard() differs from expected\n%s", diff)
+			}
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseClipboard() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseClipboard() should return not error, got %#v", err)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_SetClipboardHandler(t *testing.T) {
+	source := strings.Join([]string{
+		"copy \x1b]52;c;SGVsbG8=\x07done",
+		"\x1b]52;c;SGVsbG8gV29ybGQ=\x1b\\too large",
+		"\x1b]52;p;V29ybGQ=",
+	}, "\n")
+
+	var actual []Clipboard
+
+	filter := New()
+	filter.SetClipboardLimit(8)
+	filter.SetClipboardHandler(func(c Clipboard) error {
+		actual = append(actual, c)
+		return nil
+	})
+
+	if err := filter.Load(strings.NewReader(source)); err != nil {
+		t.Fatalf("Load() should not return error, got %v", err)
+	}
+
+	expected := []Clipboard{
+		{Position: Position{Row: 1, Col: 6}, Selection: "c", Data: []byte("Hello")},
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("clipboard payloads differ from expected\n%s", diff)
+	}
+
+	if text := filter.String(); text != "copy done\ntoo large\n" {
+		t.Errorf("String() should return %q, got %q", "copy done\ntoo large\n", text)
+	}
+}
+
+func Test_EscapeFilter_SetClipboardHandler_Error(t *testing.T) {
+	handlerError := errors.New("handler error")
+
+	filter := New()
+	filter.SetClipboardHandler(func(c Clipboard) error {
+		return handlerError
+	})
+
+	if err := filter.Load(strings.NewReader("\x1b]52;c;SGVsbG8=\x07")); err != handlerError {
+		t.Errorf("Load() should return %v, got %v", handlerError, err)
+	}
+}
+
+func Test_EscapeFilter_SetClipboardLimit_Parsing(t *testing.T) {
+	called := false
+
+	filter := New()
+	filter.SetClipboardLimit(8)
+	filter.SetClipboardHandler(func(c Clipboard) error {
+		called = true
+		return nil
+	})
+
+	filter.Write([]byte("\x1b]52;c;" + strings.Repeat("SGVsbG8g", 1<<16)))
+
+	// the payload is not accumulated beyond the limit
+	if n, max := filter.parser.data.Len(), len("52;")+clipboardPayloadLimit(8); n > max {
+		t.Errorf("data of the parser should be at most %d bytes, got %d", max, n)
+	}
+
+	if err := filter.Load(strings.NewReader("\x07done")); err != nil {
+		t.Fatalf("Load() should not return error, got %v", err)
+	}
+
+	if called {
+		t.Errorf("ClipboardHandler should not be called")
+	}
+
+	if text := filter.String(); text != "done" {
+		t.Errorf("String() should return %q, got %q", "done", text)
+	}
+}
@@ -0,0 +1,306 @@
+package escapefilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Color represents a RGB color.
+type Color struct {
+	R uint8
+	G uint8
+	B uint8
+}
+
+// String returns the color in the form of "#rrggbb".
+func (c Color) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// MarshalText returns the color in the form of "#rrggbb", which is used in JSON output.
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// colorNames maps X11 color names (lower case without spaces) to colors.
+// Only commonly used names are supported.
+var colorNames = map[string]Color{
+	"black":         {0x00, 0x00, 0x00},
+	"white":         {0xff, 0xff, 0xff},
+	"red":           {0xff, 0x00, 0x00},
+	"green":         {0x00, 0xff, 0x00},
+	"blue":          {0x00, 0x00, 0xff},
+	"yellow":        {0xff, 0xff, 0x00},
+	"cyan":          {0x00, 0xff, 0xff},
+	"magenta":       {0xff, 0x00, 0xff},
+	"gray":          {0xbe, 0xbe, 0xbe},
+	"grey":          {0xbe, 0xbe, 0xbe},
+	"darkgray":      {0xa9, 0xa9, 0xa9},
+	"darkgrey":      {0xa9, 0xa9, 0xa9},
+	"lightgray":     {0xd3, 0xd3, 0xd3},
+	"lightgrey":     {0xd3, 0xd3, 0xd3},
+	"darkred":       {0x8b, 0x00, 0x00},
+	"darkgreen":     {0x00, 0x64, 0x00},
+	"darkblue":      {0x00, 0x00, 0x8b},
+	"darkcyan":      {0x00, 0x8b, 0x8b},
+	"darkmagenta":   {0x8b, 0x00, 0x8b},
+	"lightblue":     {0xad, 0xd8, 0xe6},
+	"lightgreen":    {0x90, 0xee, 0x90},
+	"lightyellow":   {0xff, 0xff, 0xe0},
+	"lightcyan":     {0xe0, 0xff, 0xff},
+	"orange":        {0xff, 0xa5, 0x00},
+	"purple":        {0xa0, 0x20, 0xf0},
+	"brown":         {0xa5, 0x2a, 0x2a},
+	"pink":          {0xff, 0xc0, 0xcb},
+	"navy":          {0x00, 0x00, 0x80},
+	"maroon":        {0xb0, 0x30, 0x60},
+	"olive":         {0x80, 0x80, 0x00},
+	"teal":          {0x00, 0x80, 0x80},
+	"silver":        {0xc0, 0xc0, 0xc0},
+	"gold":          {0xff, 0xd7, 0x00},
+	"violet":        {0xee, 0x82, 0xee},
+	"turquoise":     {0x40, 0xe0, 0xd0},
+	"beige":         {0xf5, 0xf5, 0xdc},
+	"ivory":         {0xff, 0xff, 0xf0},
+	"wheat":         {0xf5, 0xde, 0xb3},
+	"khaki":         {0xf0, 0xe6, 0x8c},
+	"salmon":        {0xfa, 0x80, 0x72},
+	"coral":         {0xff, 0x7f, 0x50},
+	"tomato":        {0xff, 0x63, 0x47},
+	"orchid":        {0xda, 0x70, 0xd6},
+	"plum":          {0xdd, 0xa0, 0xdd},
+	"chocolate":     {0xd2, 0x69, 0x1e},
+	"firebrick":     {0xb2, 0x22, 0x22},
+	"forestgreen":   {0x22, 0x8b, 0x22},
+	"seagreen":      {0x2e, 0x8b, 0x57},
+	"skyblue":       {0x87, 0xce, 0xeb},
+	"steelblue":     {0x46, 0x82, 0xb4},
+	"royalblue":     {0x41, 0x69, 0xe1},
+	"dodgerblue":    {0x1e, 0x90, 0xff},
+	"slategray":     {0x70, 0x80, 0x90},
+	"slategrey":     {0x70, 0x80, 0x90},
+	"dimgray":       {0x69, 0x69, 0x69},
+	"dimgrey":       {0x69, 0x69, 0x69},
+	"whitesmoke":    {0xf5, 0xf5, 0xf5},
+	"gainsboro":     {0xdc, 0xdc, 0xdc},
+	"snow":          {0xff, 0xfa, 0xfa},
+	"linen":         {0xfa, 0xf0, 0xe6},
+	"midnightblue":  {0x19, 0x19, 0x70},
+	"darkslategray": {0x2f, 0x4f, 0x4f},
+	"darkslategrey": {0x2f, 0x4f, 0x4f},
+}
+
+// parseHexComponent parses a component of a color spec, which is 1 to 4 hex digits.
+// The value is scaled to 8 bits if scale is true, or the most significant 8 bits are taken otherwise.
+func parseHexComponent(str string, scale bool) (uint8, bool) {
+	if len(str) < 1 || 4 < len(str) {
+		return 0, false
+	}
+
+	v, err := strconv.ParseUint(str, 16, 16)
+	if err != nil {
+		return 0, false
+	}
+
+	bits := uint(len(str) * 4)
+
+	if scale {
+		max := uint64(1)<<bits - 1
+		return uint8((v*255 + max/2) / max), true
+	}
+
+	if bits < 8 {
+		return uint8(v << (8 - bits)), true
+	}
+
+	return uint8(v >> (bits - 8)), true
+}
+
+// parseColor parses a X11 color spec: "rgb:<r>/<g>/<b>", "#<rgb>" or a color name.
+func parseColor(spec string) (Color, error) {
+	switch {
+	case strings.HasPrefix(spec, "rgb:"):
+		components := strings.Split(spec[4:], "/")
+		if len(components) != 3 {
+			return Color{}, invalidOperatingSystemCommand
+		}
+
+		var rgb [3]uint8
+		for i, str := range components {
+			v, ok := parseHexComponent(str, true)
+			if !ok {
+				return Color{}, invalidOperatingSystemCommand
+			}
+
+			rgb[i] = v
+		}
+
+		return Color{R: rgb[0], G: rgb[1], B: rgb[2]}, nil
+	case strings.HasPrefix(spec, "#"):
+		digits := spec[1:]
+		if len(digits) == 0 || len(digits)%3 != 0 {
+			return Color{}, invalidOperatingSystemCommand
+		}
+
+		n := len(digits) / 3
+
+		var rgb [3]uint8
+		for i := range rgb {
+			v, ok := parseHexComponent(digits[i*n:(i+1)*n], false)
+			if !ok {
+				return Color{}, invalidOperatingSystemCommand
+			}
+
+			rgb[i] = v
+		}
+
+		return Color{R: rgb[0], G: rgb[1], B: rgb[2]}, nil
+	default:
+		name := strings.ToLower(strings.ReplaceAll(spec, " ", ""))
+		if c, ok := colorNames[name]; ok {
+			return c, nil
+		}
+
+		return Color{}, invalidOperatingSystemCommand
+	}
+}
+
+// Palette stores colors changed by OSC 4, OSC 10 and OSC 11.
+// Colors not changed (or reset) are not stored, which means the default of the terminal.
+type Palette struct {
+	// Colors stores the changed entries of the 256-color palette by index.
+	Colors map[int]Color `json:"colors,omitempty"`
+	// Foreground is the default foreground color (nil if not changed).
+	Foreground *Color `json:"foreground,omitempty"`
+	// Background is the default background color (nil if not changed).
+	Background *Color `json:"background,omitempty"`
+}
+
+// isEmpty reports whether no colors are changed.
+func (p *Palette) isEmpty() bool {
+	return len(p.Colors) == 0 && p.Foreground == nil && p.Background == nil
+}
+
+// setColors applys the parameter of OSC 4, which is <index> ; <spec> [; <index> ; <spec>]*.
+// Queries ("?") and indices out of the 256-color palette are ignored.
+// Nothing is changed if any pair is invalid.
+func (p *Palette) setColors(param string) error {
+	params := strings.Split(param, ";")
+	if len(params)%2 != 0 {
+		return invalidOperatingSystemCommand
+	}
+
+	colors := map[int]Color{}
+
+	for i := 0; i < len(params); i += 2 {
+		index, err := strconv.Atoi(params[i])
+		if err != nil || index < 0 {
+			return invalidOperatingSystemCommand
+		}
+
+		if params[i+1] == "?" || index > 255 {
+			continue
+		}
+
+		c, err := parseColor(params[i+1])
+		if err != nil {
+			return err
+		}
+
+		colors[index] = c
+	}
+
+	if p.Colors == nil && len(colors) > 0 {
+		p.Colors = map[int]Color{}
+	}
+
+	for index, c := range colors {
+		p.Colors[index] = c
+	}
+
+	return nil
+}
+
+// resetColors applys the parameter of OSC 104, which is [<index> [; <index>]*].
+// All the entries are reset if no indices are specified.
+func (p *Palette) resetColors(param string) error {
+	if param == "" {
+		p.Colors = nil
+		return nil
+	}
+
+	var indices []int
+	for _, str := range strings.Split(param, ";") {
+		index, err := strconv.Atoi(str)
+		if err != nil {
+			return invalidOperatingSystemCommand
+		}
+
+		indices = append(indices, index)
+	}
+
+	for _, index := range indices {
+		delete(p.Colors, index)
+	}
+
+	return nil
+}
+
+// setDynamicColors applys the parameter of OSC 10 (first = 10) or OSC 11 (first = 11).
+// Following specs set the next dynamic colors like xterm, e.g. OSC 10 ; <fg> ; <bg>.
+// Queries ("?") and dynamic colors other than the foreground and the background are ignored.
+func (p *Palette) setDynamicColors(first int, param string) error {
+	targets := []**Color{&p.Foreground, &p.Background}[first-10:]
+
+	var colors []*Color
+	for i, spec := range strings.Split(param, ";") {
+		if i >= len(targets) {
+			break
+		}
+
+		if spec == "?" {
+			colors = append(colors, nil)
+			continue
+		}
+
+		c, err := parseColor(spec)
+		if err != nil {
+			return err
+		}
+
+		colors = append(colors, &c)
+	}
+
+	for i, c := range colors {
+		if c != nil {
+			*targets[i] = c
+		}
+	}
+
+	return nil
+}
+
+// Palette returns the colors changed by the input.
+func (f *EscapeFilter) Palette() Palette {
+	var p Palette
+
+	if f.palette.Foreground != nil {
+		fg := *f.palette.Foreground
+		p.Foreground = &fg
+	}
+
+	if f.palette.Background != nil {
+		bg := *f.palette.Background
+		p.Background = &bg
+	}
+
+	if len(f.palette.Colors) > 0 {
+		p.Colors = map[int]Color{}
+		for index, c := range f.palette.Colors {
+			p.Colors[index] = c
+		}
+	}
+
+	return p
+}
@@ -0,0 +1,114 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_parseColor(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected Color
+		isError  bool
+	}{
+		{spec: "rgb:ff/80/00", expected: Color{0xff, 0x80, 0x00}},
+		{spec: "rgb:ffff/8080/0000", expected: Color{0xff, 0x80, 0x00}},
+		{spec: "rgb:f/8/0", expected: Color{0xff, 0x88, 0x00}},
+		{spec: "rgb:fff/800/000", expected: Color{0xff, 0x80, 0x00}},
+		{spec: "#ff8000", expected: Color{0xff, 0x80, 0x00}},
+		{spec: "#f80", expected: Color{0xf0, 0x80, 0x00}},
+		{spec: "#ffff80000000", expected: Color{0xff, 0x80, 0x00}},
+		{spec: "orange", expected: Color{0xff, 0xa5, 0x00}},
+		{spec: "Light Gray", expected: Color{0xd3, 0xd3, 0xd3}},
+		{spec: "rgb:ff/80", isError: true},
+		{spec: "rgb:fffff/80/00", isError: true},
+		{spec: "rgb:gg/80/00", isError: true},
+		{spec: "#ff80", isError: true},
+		{spec: "#", isError: true},
+		{spec: "nosuchcolor", isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("spec=%q", tt.spec), func(t *testing.T) {
+			actual, err := parseColor(tt.spec)
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseColor() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseColor() should return not error, got %#v", err)
+			}
+
+			if actual != tt.expected {
+				t.Errorf("parseColor() should return %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Palette_setColors(t *testing.T) {
+	tests := []struct {
+		param    string
+		expected map[int]Color
+		isError  bool
+	}{
+		{param: "1;#ff0000", expected: map[int]Color{1: {0xff, 0x00, 0x00}, 2: {0x00, 0x00, 0xff}}},
+		{param: "1;#ff0000;3;green", expected: map[int]Color{1: {0xff, 0x00, 0x00}, 2: {0x00, 0x00, 0xff}, 3: {0x00, 0xff, 0x00}}},
+		{param: "1;?;256;#ff0000", expected: map[int]Color{2: {0x00, 0x00, 0xff}}},
+		{param: "1;#ff0000;3", expected: map[int]Color{2: {0x00, 0x00, 0xff}}, isError: true},
+		{param: "1;#ff0000;3;nosuchcolor", expected: map[int]Color{2: {0x00, 0x00, 0xff}}, isError: true},
+		{param: "a;#ff0000", expected: map[int]Color{2: {0x00, 0x00, 0xff}}, isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q", tt.param), func(t *testing.T) {
+			p := &Palette{Colors: map[int]Color{2: {0x00, 0x00, 0xff}}}
+			err := p.setColors(tt.param)
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("setColors() should return error")
+			case !tt.isError && isError:
+				t.Errorf("setColors() should return not error, got %#v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, p.Colors); diff != "" {
+				t.Errorf("colors differ from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_Palette(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b]4;1;rgb:cc/00/00;2;#00cc00\x07\x1b]10;white;black\x07\x1b]11;?\x07themed",
+		"\x1b]104;2\x07\x1b]4;9;#ff0000\x1b\\\x1b]104\x1b\\\x1b]4;3;yellow\x07\x1b]110\x07reset",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	black := Color{0x00, 0x00, 0x00}
+	expected := Palette{
+		Colors:     map[int]Color{3: {0xff, 0xff, 0x00}},
+		Background: &black,
+	}
+
+	if diff := cmp.Diff(expected, filter.Palette()); diff != "" {
+		t.Errorf("Palette() differs from expected\n%s", diff)
+	}
+
+	var sb strings.Builder
+	if err := filter.WriteHTML(&sb); err != nil {
+		t.Fatalf("WriteHTML() returned error: %v", err)
+	}
+
+	html := "<pre style=\"background-color: #000000; --color-3: #ffff00\">themed\nreset</pre>\n"
+	if sb.String() != html {
+		t.Errorf("WriteHTML() should write %q, got %q", html, sb.String())
+	}
+}
@@ -1,7 +1,6 @@
 package escapefilter
 
 import (
-	"bufio"
 	"errors"
 	"strings"
 )
@@ -28,54 +27,10 @@ func (c *controlSequence) String() string {
 // invalidControlSequence represents the error of parsing control sequence.
 var invalidControlSequence = errors.New("invalid control sequence")
 
-// readControlSequence reads a control sequence from the Reader.
-func readControlSequence(rd *bufio.Reader) (*controlSequence, error) {
-	cs := &controlSequence{}
-
-	const (
-		PARAMETER = iota
-		INTERMEDIATE
-		FINAL
-		END
-	)
-
-	for state := PARAMETER; state != END; {
-		r, s, err := rd.ReadRune()
-		if s == 0 {
-			return cs, err
-		}
-
-		switch state {
-		case PARAMETER:
-			if '\u0030' <= r && r <= '\u003F' {
-				cs.param += string(r)
-			} else {
-				rd.UnreadRune()
-				state = INTERMEDIATE
-			}
-		case INTERMEDIATE:
-			if '\u0020' <= r && r <= '\u002F' {
-				cs.intermediate += string(r)
-			} else {
-				rd.UnreadRune()
-				state = FINAL
-			}
-		case FINAL:
-			if '\u0040' <= r && r <= '\u007E' {
-				cs.final += string(r)
-				state = END
-			} else {
-				rd.UnreadRune()
-				return cs, invalidControlSequence
-			}
-		}
-	}
-
-	return cs, nil
-}
+// processControlSequence applys the effects of the control sequence to the filter.
+func processControlSequence(f *EscapeFilter, cs *controlSequence) error {
+	s := f.screen
 
-// processControlSequence applys the effects of the control sequence to screen.
-func processControlSequence(s *Screen, cs *controlSequence) error {
 	switch cs.final {
 	case "A": // CUU
 		n, err := parseInt(cs.param, 1)
@@ -177,6 +132,39 @@ func processControlSequence(s *Screen, cs *controlSequence) error {
 		case 2:
 			s.EraseLine()
 		}
+	case "t": // XTWINOPS
+		params := strings.Split(cs.param, ";")
+
+		ns := make([]int, len(params))
+		for i, param := range params {
+			var err error
+			ns[i], err = parseInt(param, 0)
+			if err != nil {
+				return invalidControlSequence
+			}
+		}
+
+		// only title stack operations are supported
+		// 22;0 / 23;0 (or no second parameter) means both the icon name and the window title
+		if ns[0] != 22 && ns[0] != 23 {
+			break
+		}
+
+		which := 0
+		if len(ns) >= 2 {
+			which = ns[1]
+		}
+
+		window := which == 0 || which == 2
+		icon := which == 0 || which == 1
+
+		if ns[0] == 22 {
+			f.titles.push()
+		} else {
+			for _, tc := range f.titles.pop(s.Position(), window, icon) {
+				f.addEvent(tc)
+			}
+		}
 	case "Z": // CBT
 		n, err := parseInt(cs.param, 1)
 		if err != nil {
@@ -1,11 +1,8 @@
 package escapefilter
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/google/go-cmp/cmp"
-	"io"
-	"strings"
 	"testing"
 )
 
@@ -37,163 +34,56 @@ func Test_controlSequence_String(t *testing.T) {
 	}
 }
 
-func Test_readControlSequence(t *testing.T) {
-	tests := []struct {
-		str     string
-		cs      *controlSequence
-		next    rune
-		isError bool
-	}{
-		// "\u001B[" is for the sake of clarity, supposed to have benn read before.
-		// next: '\u0000' means Reader should be at EOF after read.
-		{
-			str:     "\u001B[Aabc",
-			cs:      &controlSequence{final: "A"},
-			next:    'a',
-			isError: false,
-		},
-		{
-			str:     "\u001B[1;31mdef",
-			cs:      &controlSequence{param: "1;31", final: "m"},
-			next:    'd',
-			isError: false,
-		},
-		{
-			str:     "\u001B[0\"qghi",
-			cs:      &controlSequence{param: "0", intermediate: "\"", final: "q"},
-			next:    'g',
-			isError: false,
-		},
-		{
-			str:     "\u001B[A",
-			cs:      &controlSequence{final: "A"},
-			next:    '\u0000',
-			isError: false,
-		},
-		{
-			str:     "\u001B[1;31m",
-			cs:      &controlSequence{param: "1;31", final: "m"},
-			next:    '\u0000',
-			isError: false,
-		},
-		{
-			str:     "\u001B[0\"q",
-			cs:      &controlSequence{param: "0", intermediate: "\"", final: "q"},
-			next:    '\u0000',
-			isError: false,
-		},
-		{
-			str:     "\u001B[",
-			cs:      &controlSequence{},
-			next:    '\u0000',
-			isError: true,
-		},
-		{
-			str:     "\u001B[123",
-			cs:      &controlSequence{param: "123"},
-			next:    '\u0000',
-			isError: true,
-		},
-		{
-			str:     "\u001B[0\"",
-			cs:      &controlSequence{param: "0", intermediate: "\""},
-			next:    '\u0000',
-			isError: true,
-		},
-		{
-			str:     "\u001B[1;31あ",
-			cs:      &controlSequence{param: "1;31"},
-			next:    'あ',
-			isError: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(fmt.Sprintf("str=%q", tt.str), func(t *testing.T) {
-			rd := bufio.NewReader(strings.NewReader(tt.str[2:]))
-			cs, err := readControlSequence(rd)
-
-			opt := cmp.AllowUnexported(*tt.cs)
-			if diff := cmp.Diff(tt.cs, cs, opt); diff != "" {
-				t.Errorf("readControlSequence() differs from expected\n%s", diff)
-			}
-
-			isError := err != nil
-			switch {
-			case tt.isError && !isError:
-				t.Errorf("readControlSequence() should return error")
-			case !tt.isError && isError:
-				t.Errorf("readControlSequence() should return not error, got %#v", err)
-			}
-
-			r, s, err := rd.ReadRune()
-			if err != nil && err != io.EOF {
-				t.Fatalf("unexpected error occurred: %v", err)
-			}
-
-			eof := s == 0 && err == io.EOF
-			switch {
-			case tt.next == '\u0000' && !eof:
-				t.Errorf("next rune should be EOF, got '%q'", r)
-			case tt.next != '\u0000' && eof:
-				t.Errorf("next rune should be '%q', got EOF", tt.next)
-			case tt.next != '\u0000' && r != tt.next:
-				t.Errorf("next rune should be '%q', got '%q'", tt.next, r)
-			}
-		})
-	}
-}
-
 func Test_processControlSequence(t *testing.T) {
 	tests := []struct {
-		lines    []string
+		lines    []cells
 		row      int
 		col      int
 		cs       *controlSequence
 		expected *Screen
 	}{
 		{
-			lines: []string{"Hello World", "こんにちはABC世界"},
+			lines: newLines("Hello World", "こんにちはABC世界"),
 			row:   2,
 			col:   18,
 			cs:    &controlSequence{param: "7", final: "D"},
 			expected: &Screen{
 				row:   2,
 				col:   11,
-				lines: []string{"Hello World", "こんにちはABC世界"},
+				lines: newLines("Hello World", "こんにちはABC世界"),
 			},
 		},
 		{
-			lines: []string{"Hello World", "こんにちはABC世界"},
+			lines: newLines("Hello World", "こんにちはABC世界"),
 			row:   2,
 			col:   11,
 			cs:    &controlSequence{param: "0", final: "K"},
 			expected: &Screen{
 				row:   2,
 				col:   11,
-				lines: []string{"Hello World", "こんにちは"},
+				lines: newLines("Hello World", "こんにちは"),
 			},
 		},
 		{
-			lines: []string{"Hello World", "こんにちは"},
+			lines: newLines("Hello World", "こんにちは"),
 			row:   2,
 			col:   11,
 			cs:    &controlSequence{param: "1;31", final: "m"},
 			expected: &Screen{
 				row:   2,
 				col:   11,
-				lines: []string{"Hello World", "こんにちは"},
+				lines: newLines("Hello World", "こんにちは"),
 			},
 		},
 		{
-			lines: []string{"Hello World", "こんにちは"},
+			lines: newLines("Hello World", "こんにちは"),
 			row:   2,
 			col:   11,
 			cs:    &controlSequence{param: "1;31"},
 			expected: &Screen{
 				row:   2,
 				col:   11,
-				lines: []string{"Hello World", "こんにちは"},
+				lines: newLines("Hello World", "こんにちは"),
 			},
 		},
 	}
@@ -201,8 +91,9 @@ func Test_processControlSequence(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("lines=%q,row=%d,col=%d,cs=%q", tt.lines, tt.row, tt.col, tt.cs), func(t *testing.T) {
 			s := &Screen{lines: tt.lines, row: tt.row, col: tt.col}
+			f := &EscapeFilter{screen: s}
 
-			err := processControlSequence(s, tt.cs)
+			err := processControlSequence(f, tt.cs)
 			if err != nil {
 				t.Errorf("processControlSequence() should not return error, got %#v", err)
 			}
@@ -214,3 +105,40 @@ func Test_processControlSequence(t *testing.T) {
 		})
 	}
 }
+
+func Test_processControlSequence_TitleStack(t *testing.T) {
+	tests := []struct {
+		cs       *controlSequence
+		title    string
+		iconName string
+	}{
+		{cs: &controlSequence{param: "22", final: "t"}, title: "new title", iconName: "new icon"},
+		{cs: &controlSequence{param: "23", final: "t"}, title: "old title", iconName: "old icon"},
+		{cs: &controlSequence{param: "23;0", final: "t"}, title: "old title", iconName: "old icon"},
+		{cs: &controlSequence{param: "23;1", final: "t"}, title: "new title", iconName: "old icon"},
+		{cs: &controlSequence{param: "23;2", final: "t"}, title: "old title", iconName: "new icon"},
+		{cs: &controlSequence{param: "8;24;80", final: "t"}, title: "new title", iconName: "new icon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("cs=%q", tt.cs), func(t *testing.T) {
+			f := New()
+			f.titles = titles{
+				titleState: titleState{title: "new title", iconName: "new icon"},
+				stack:      []titleState{{title: "old title", iconName: "old icon"}},
+			}
+
+			if err := processControlSequence(f, tt.cs); err != nil {
+				t.Errorf("processControlSequence() should not return error, got %#v", err)
+			}
+
+			if title := f.Title(); title != tt.title {
+				t.Errorf("Title() should return %q, got %q", tt.title, title)
+			}
+
+			if iconName := f.IconName(); iconName != tt.iconName {
+				t.Errorf("IconName() should return %q, got %q", tt.iconName, iconName)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package escapefilter
+
+import (
+	"strings"
+)
+
+// deviceControlString represents device control string, which is DCS <param>* <intermediate>* <final> <data>* ST.
+type deviceControlString struct {
+	param        string
+	intermediate string
+	final        string
+	data         string
+	terminator   string
+}
+
+// String returns the string representation of the device control string.
+func (c *deviceControlString) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\u001BP")
+	sb.WriteString(c.param)
+	sb.WriteString(c.intermediate)
+	sb.WriteString(c.final)
+	sb.WriteString(c.data)
+	sb.WriteString(c.terminator)
+
+	return sb.String()
+}
+
+// controlString represents SOS, PM or APC string, which is ESC <introducer> <data>* ST.
+type controlString struct {
+	introducer string
+	data       string
+	terminator string
+}
+
+// String returns the string representation of the control string.
+func (c *controlString) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\u001B")
+	sb.WriteString(c.introducer)
+	sb.WriteString(c.data)
+	sb.WriteString(c.terminator)
+
+	return sb.String()
+}
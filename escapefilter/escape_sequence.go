@@ -1,71 +1,95 @@
 package escapefilter
 
 import (
-	"bufio"
-	"errors"
-	"io"
-	"unicode/utf8"
+	"strings"
 )
 
-// invalidEscapeSequence represents the error of parsing escape sequence, which is ESC <char>.
-var invalidEscapeSequence = errors.New("invalid escape sequence")
+// escapeSequence represents escape sequence, which is ESC <intermediate>* <final>.
+type escapeSequence struct {
+	intermediate string
+	final        string
+}
 
-// readEscapeSequence reads an escape sequence from the Reader.
-func readEscapeSequence(rd *bufio.Reader) (rune, error) {
-	r, size, err := rd.ReadRune()
-	if size == 0 {
-		if err == io.EOF {
-			return utf8.RuneError, invalidEscapeSequence
-		} else {
-			return utf8.RuneError, err
-		}
-	}
+// String returns the string representation of the escape sequence.
+func (e *escapeSequence) String() string {
+	var sb strings.Builder
 
-	if !('\u0040' <= r && r <= '\u005F') {
-		rd.UnreadRune()
-		return utf8.RuneError, invalidEscapeSequence
-	}
+	sb.WriteString("\u001B")
+	sb.WriteString(e.intermediate)
+	sb.WriteString(e.final)
 
-	return r, nil
+	return sb.String()
 }
 
-// processEscapeSequence applys the effects of the escape sequence to the screen.
-func processEscapeSequence(s *Screen, rd *bufio.Reader, r rune) error {
-	switch r {
-	case '[': // CSI
-		cs, err := readControlSequence(rd)
-		if err != nil {
-			if err == invalidControlSequence {
-				return nil // just ignore
-			} else {
-				return err
-			}
-		}
+// processEscapeSequence applys the effects of the escape sequence to the filter.
+func processEscapeSequence(f *EscapeFilter, es *escapeSequence) error {
+	s := f.screen
 
-		if err := processControlSequence(s, cs); err != nil {
-			if err == invalidControlSequence {
-				return nil // just ignore
-			} else {
-				return err
-			}
+	switch es.intermediate {
+	case "":
+		return processEscapeSequenceWithoutIntermediate(f, es)
+	case " ":
+		// ACS, S7C1T, S8C1T, etc. are not needed to filter text, just ignore
+	case "#":
+		switch es.final {
+		case "3": // DECDHL (top half)
+			s.SetLineRendition(DoubleHeightTop)
+		case "4": // DECDHL (bottom half)
+			s.SetLineRendition(DoubleHeightBottom)
+		case "5": // DECSWL
+			s.SetLineRendition(SingleWidth)
+		case "6": // DECDWL
+			s.SetLineRendition(DoubleWidth)
+		case "8": // DECALN
+			s.FillScreen('E')
+		default:
+			// unsupported, just ignore
 		}
-	case ']': // OSC
-		osc, err := readOperatingSystemCommand(rd)
-		if err != nil {
-			if err == invalidOperatingSystemCommand {
-				return nil // just ignore
-			} else {
-				return err
-			}
+	case "%": // DOCS
+		switch es.final {
+		case "G": // UTF-8
+			f.utf8 = true
+		case "@": // return to ISO 2022 (ISO 8859-1)
+			f.utf8 = false
+		default:
+			// unsupported, just ignore
 		}
+	case "(": // SCS G0 (94 characters)
+		s.DesignateCharset(0, parseCharset(es.final, false))
+	case ")": // SCS G1 (94 characters)
+		s.DesignateCharset(1, parseCharset(es.final, false))
+	case "*": // SCS G2 (94 characters)
+		s.DesignateCharset(2, parseCharset(es.final, false))
+	case "+": // SCS G3 (94 characters)
+		s.DesignateCharset(3, parseCharset(es.final, false))
+	case "-": // SCS G1 (96 characters)
+		s.DesignateCharset(1, parseCharset(es.final, true))
+	case ".": // SCS G2 (96 characters)
+		s.DesignateCharset(2, parseCharset(es.final, true))
+	case "/": // SCS G3 (96 characters)
+		s.DesignateCharset(3, parseCharset(es.final, true))
+	default:
+		// unsupported, just ignore
+	}
 
-		if err := processOperatingSystemCommand(s, osc); err != nil {
-			if err == invalidOperatingSystemCommand {
-				return nil // just ignore
-			} else {
-				return err
-			}
-		}
+	return nil
+}
+
+// processEscapeSequenceWithoutIntermediate applys the effects of the escape sequence without intermediates.
+func processEscapeSequenceWithoutIntermediate(f *EscapeFilter, es *escapeSequence) error {
+	s := f.screen
+
+	switch es.final {
+	case "=", ">": // DECKPAM, DECKPNM
+		// keypad modes do not affect output, just ignore
+	case "N": // SS2
+		s.SingleShift(2)
+	case "O": // SS3
+		s.SingleShift(3)
+	case "n": // LS2
+		s.LockingShift(2)
+	case "o": // LS3
+		s.LockingShift(3)
 	default:
 		// unsupported, just ignore
 	}
@@ -1,91 +1,156 @@
 package escapefilter
 
 import (
-	"bufio"
 	"fmt"
-	"io"
-	"strings"
+	"github.com/google/go-cmp/cmp"
 	"testing"
-	"unicode/utf8"
 )
 
-func Test_readEscapeSequence(t *testing.T) {
+func Test_escapeSequence_String(t *testing.T) {
 	tests := []struct {
-		str     string
-		r       rune
-		next    rune
-		isError bool
+		es       *escapeSequence
+		expected string
 	}{
-		// "\u001B[" is for the sake of clarity, supposed to have benn read before.
-		// next: '\u0000' means Reader should be at EOF after read.
 		{
-			str:     "\u001BNabc",
-			r:       'N',
-			next:    'a',
-			isError: false,
+			es:       &escapeSequence{final: "N"},
+			expected: "\u001BN",
 		},
 		{
-			str:     "\u001B]abc",
-			r:       ']',
-			next:    'a',
-			isError: false,
+			es:       &escapeSequence{intermediate: "(", final: "0"},
+			expected: "\u001B(0",
 		},
 		{
-			str:     "\u001BN",
-			r:       'N',
-			next:    '\u0000',
-			isError: false,
+			es:       &escapeSequence{intermediate: " ", final: "F"},
+			expected: "\u001B F",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("escapeSequence=%#v", tt.es), func(t *testing.T) {
+			if str := tt.es.String(); str != tt.expected {
+				t.Errorf("String() should return %q, got %q", tt.expected, str)
+			}
+		})
+	}
+}
+
+func Test_processEscapeSequence(t *testing.T) {
+	tests := []struct {
+		es       *escapeSequence
+		expected *Screen
+	}{
+		{
+			es: &escapeSequence{intermediate: "(", final: "0"},
+			expected: &Screen{
+				row:      1,
+				col:      1,
+				charsets: [4]Charset{CharsetDECSpecialGraphics, CharsetASCII, CharsetASCII, CharsetASCII},
+			},
+		},
+		{
+			es: &escapeSequence{intermediate: "+", final: "A"},
+			expected: &Screen{
+				row:      1,
+				col:      1,
+				charsets: [4]Charset{CharsetASCII, CharsetASCII, CharsetASCII, CharsetUK},
+			},
+		},
+		{
+			es: &escapeSequence{intermediate: "-", final: "A"},
+			expected: &Screen{
+				row:      1,
+				col:      1,
+				charsets: [4]Charset{CharsetASCII, CharsetLatin1Supplement, CharsetASCII, CharsetASCII},
+			},
 		},
 		{
-			str:     "\u001B[",
-			r:       '[',
-			next:    '\u0000',
-			isError: false,
+			es: &escapeSequence{final: "n"},
+			expected: &Screen{
+				row: 1,
+				col: 1,
+				gl:  2,
+			},
 		},
 		{
-			str:     "\u001B",
-			r:       utf8.RuneError,
-			next:    '\u0000',
-			isError: true,
+			es: &escapeSequence{final: "O"},
+			expected: &Screen{
+				row:         1,
+				col:         1,
+				singleShift: 3,
+			},
 		},
 		{
-			str:     "\u001B0",
-			r:       utf8.RuneError,
-			next:    '0',
-			isError: true,
+			es: &escapeSequence{intermediate: "#", final: "3"},
+			expected: &Screen{
+				row:        1,
+				col:        1,
+				renditions: map[int]LineRendition{1: DoubleHeightTop},
+			},
+		},
+		{
+			es: &escapeSequence{intermediate: "#", final: "6"},
+			expected: &Screen{
+				row:        1,
+				col:        1,
+				renditions: map[int]LineRendition{1: DoubleWidth},
+			},
+		},
+		{
+			es: &escapeSequence{intermediate: "#", final: "5"},
+			expected: &Screen{
+				row: 1,
+				col: 1,
+			},
+		},
+		{
+			es: &escapeSequence{final: "="},
+			expected: &Screen{
+				row: 1,
+				col: 1,
+			},
 		},
 	}
 
 	for _, tt := range tests {
-		t.Run(fmt.Sprintf("str=%q", tt.str), func(t *testing.T) {
-			rd := bufio.NewReader(strings.NewReader(tt.str[1:]))
-			r, err := readEscapeSequence(rd)
+		t.Run(fmt.Sprintf("es=%q", tt.es), func(t *testing.T) {
+			f := New()
+			s := f.screen
 
-			if r != tt.r  {
-				t.Errorf("readEscapeSequence() should return %q, got %q", tt.r, r)
+			if err := processEscapeSequence(f, tt.es); err != nil {
+				t.Errorf("processEscapeSequence() should not return error, got %#v", err)
 			}
 
-			isError := err != nil
-			switch {
-			case tt.isError && !isError:
-				t.Errorf("readEscapeSequence() should return error")
-			case !tt.isError && isError:
-				t.Errorf("readEscapeSequence() should return not error, got %#v", err)
+			opt := cmp.AllowUnexported(*tt.expected)
+			if diff := cmp.Diff(tt.expected, s, opt); diff != "" {
+				t.Errorf("Screen differs from expected\n%s", diff)
 			}
+		})
+	}
+}
+
+func Test_processEscapeSequence_DOCS(t *testing.T) {
+	tests := []struct {
+		utf8     bool
+		es       *escapeSequence
+		expected bool
+	}{
+		{utf8: true, es: &escapeSequence{intermediate: "%", final: "@"}, expected: false},
+		{utf8: false, es: &escapeSequence{intermediate: "%", final: "G"}, expected: true},
+		{utf8: true, es: &escapeSequence{intermediate: "%", final: "G"}, expected: true},
+		{utf8: false, es: &escapeSequence{intermediate: "%", final: "8"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("utf8=%t,es=%q", tt.utf8, tt.es), func(t *testing.T) {
+			f := New()
+			f.utf8 = tt.utf8
 
-			r, s, err := rd.ReadRune()
-			if err != nil && err != io.EOF {
-				t.Fatalf("unexpected error occurred: %v", err)
+			if err := processEscapeSequence(f, tt.es); err != nil {
+				t.Errorf("processEscapeSequence() should not return error, got %#v", err)
 			}
 
-			eof := s == 0 && err == io.EOF
-			switch {
-			case tt.next == '\u0000' && !eof:
-				t.Errorf("next rune should be EOF, got '%q'", r)
-			case tt.next != '\u0000' && eof:
-				t.Errorf("next rune should be '%q', got EOF", tt.next)
-			case tt.next != '\u0000' && r != tt.next:
-				t.Errorf("next rune should be '%q', got '%q'", tt.next, r)
+			if f.utf8 != tt.expected {
+				t.Errorf("utf8 should be %t, got %t", tt.expected, f.utf8)
 			}
 		})
 	}
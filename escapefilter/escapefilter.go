@@ -2,7 +2,11 @@ package escapefilter
 
 import (
 	"bufio"
+	"context"
 	"io"
+	"sort"
+	"unicode"
+	"unicode/utf8"
 )
 
 // processRune applys the effects to the Screen if the rune is a control character.
@@ -21,7 +25,16 @@ func processRune(s *Screen, r rune) {
 		s.MoveCursor(s.Row()+1, s.Col())
 	case '\u000D': // CR
 		s.MoveCursor(s.Row(), 1)
+	case '\u000E': // SO (LS1)
+		s.LockingShift(1)
+	case '\u000F': // SI (LS0)
+		s.LockingShift(0)
 	default:
+		// other control characters (e.g. BEL) are ignored, not to consume a single shift
+		if unicode.IsControl(r) {
+			return
+		}
+
 		s.PutRune(r)
 	}
 }
@@ -29,43 +42,252 @@ func processRune(s *Screen, r rune) {
 // EscapeFilter stores virtual screen and process text files contains ANSI escape code.
 type EscapeFilter struct {
 	screen *Screen
+
+	// utf8 indicates whether the input is decoded as UTF-8 (or ISO 8859-1 otherwise).
+	utf8 bool
+
+	// parser is the parser keeping the sequence being parsed across writes.
+	parser *parser
+	// partial is the incomplete UTF-8 character at the end of the last write.
+	partial []byte
+
+	// sequences is the number of sequences processed, for LoadProgress.
+	sequences int
+	// loadProgressHandler is called with the progress of loading (nil if not set).
+	loadProgressHandler LoadProgressHandler
+
+	// stream stores the state of the streaming mode (nil if not streaming).
+	stream *stream
+
+	// handlers stores SequenceHandlers registered by applications.
+	handlers sequenceHandlers
+
+	// titles stores the window title and the icon name.
+	titles titles
+
+	// events stores events in order of appearance.
+	events []Event
+	// transcript stores the commands built from the shell integration marks in events.
+	transcript transcript
+
+	// palette stores colors changed by OSC.
+	palette Palette
+
+	// clipboardHandler is called with each clipboard payload (nil if not set).
+	clipboardHandler ClipboardHandler
+	// clipboardLimit is the maximum size of a clipboard payload in bytes.
+	clipboardLimit int
+
+	// inlineFileHandler is called with each inline file (nil if not set).
+	inlineFileHandler InlineFileHandler
+	// inlineFileLimit is the maximum size of an inline file in bytes.
+	inlineFileLimit int
 }
 
 // New returns a new EscapeFilter.
 func New() *EscapeFilter {
-	return &EscapeFilter{screen: NewScreen()}
+	f := &EscapeFilter{
+		screen:          NewScreen(),
+		utf8:            true,
+		clipboardLimit:  DefaultClipboardLimit,
+		inlineFileLimit: DefaultInlineFileLimit,
+	}
+	f.parser = newParser(f)
+	f.parser.oscLimit = f.oscLimit
+
+	return f
+}
+
+// readRune reads a rune as UTF-8, or as ISO 8859-1 if utf8 is false.
+func readRune(rd *bufio.Reader, utf8 bool) (rune, int, error) {
+	if utf8 {
+		return rd.ReadRune()
+	}
+
+	b, err := rd.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return rune(b), 1, nil
 }
 
 // Load loads contents from the Reader.
+// An incomplete sequence at the end is ignored.
 func (f *EscapeFilter) Load(rd io.Reader) error {
-	brd := bufio.NewReader(rd)
+	return f.LoadContext(context.Background(), rd)
+}
 
-	for {
-		r, s, err := brd.ReadRune()
-		if s == 0 {
-			if err == io.EOF {
+// Write processes the bytes as a part of the input.
+// An incomplete sequence or UTF-8 character at the end is kept until the following Write.
+func (f *EscapeFilter) Write(b []byte) (int, error) {
+	buf := b
+	if len(f.partial) > 0 {
+		buf = append(f.partial, b...)
+	}
+	f.partial = nil
+
+	for len(buf) > 0 {
+		// the coding system may be changed by the previous character
+		r, s := rune(buf[0]), 1
+		if f.utf8 {
+			if !utf8.FullRune(buf) {
+				f.partial = append([]byte(nil), buf...)
 				break
 			}
-			return err
+
+			r, s = utf8.DecodeRune(buf)
 		}
 
-		if r == '\u001B' {
-			es, err := readEscapeSequence(brd)
-			if err != nil {
-				if err == invalidEscapeSequence {
-					// ignore ESC
-					continue
-				} else {
-					return err
-				}
+		buf = buf[s:]
+
+		err := f.advance(r, s)
+		if err == nil && f.stream != nil {
+			err = f.flushStream()
+		}
+
+		if err != nil {
+			n := len(b) - len(buf)
+			if n < 0 {
+				n = 0
 			}
 
-			processEscapeSequence(f.screen, brd, es)
-		} else {
-			processRune(f.screen, r)
+			return n, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// advance processes a character, which is size bytes in the input.
+// Exceeding a limit is reported as an error in the strict policy.
+func (f *EscapeFilter) advance(r rune, size int) error {
+	err := f.parser.advance(r, size)
+	if err == dataTooLong {
+		f.screen.exceed(LimitPayload, f.parser.maxData)
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return f.screen.takeError()
+}
+
+// Close ends the input written so far like Load.
+// An incomplete sequence at the end is discarded, and an incomplete UTF-8 character is processed as U+FFFD.
+// In the streaming mode, the rest of the screen is written out and the streaming mode ends.
+// The EscapeFilter can still be written after Close, as a new input following the previous one.
+func (f *EscapeFilter) Close() error {
+	if err := f.endInput(); err != nil {
+		return err
+	}
+
+	if f.stream != nil {
+		return f.closeStream()
+	}
+
+	return nil
+}
+
+// endInput discards an incomplete sequence, and processes an incomplete UTF-8 character as U+FFFD.
+func (f *EscapeFilter) endInput() error {
+	partial := f.partial
+	f.partial = nil
+
+	for len(partial) > 0 {
+		r, s := utf8.DecodeRune(partial)
+		partial = partial[s:]
+
+		if err := f.advance(r, s); err != nil {
+			return err
 		}
 	}
 
+	f.parser.reset()
+
+	return nil
+}
+
+// print puts the character to the screen.
+func (f *EscapeFilter) print(r rune) error {
+	f.screen.PutRune(r)
+	return nil
+}
+
+// execute applys the effects of the control character to the screen.
+func (f *EscapeFilter) execute(r rune) error {
+	if '\u0080' <= r && r <= '\u009F' {
+		// C1 is equivalent to ESC Fe
+		return f.escapeSequence(&escapeSequence{final: string(r - 0x40)}, f.parser.offset)
+	}
+
+	switch r {
+	case '\u0018', '\u001A': // CAN, SUB
+		// only cancel sequences
+	default:
+		processRune(f.screen, r)
+	}
+
+	return nil
+}
+
+// dispatchEscapeSequence applys the effects of the escape sequence to the filter.
+func (f *EscapeFilter) dispatchEscapeSequence(es *escapeSequence) error {
+	return f.escapeSequence(es, f.parser.start)
+}
+
+// escapeSequence applys the effects of the escape sequence starting at the offset to the filter.
+func (f *EscapeFilter) escapeSequence(es *escapeSequence, start int64) error {
+	f.sequences++
+
+	if handled, err := f.handle(f.handlers.escape, es.intermediate+es.final, escapeSequenceToken(es), start); handled || err != nil {
+		return err
+	}
+
+	return processEscapeSequence(f, es)
+}
+
+// dispatchControlSequence applys the effects of the control sequence to the filter.
+func (f *EscapeFilter) dispatchControlSequence(cs *controlSequence) error {
+	f.sequences++
+
+	if handled, err := f.handle(f.handlers.control, cs.intermediate+cs.final, controlSequenceToken(cs), f.parser.start); handled || err != nil {
+		return err
+	}
+
+	if err := processControlSequence(f, cs); err != nil && err != invalidControlSequence {
+		return err
+	}
+
+	return nil
+}
+
+// dispatchOperatingSystemCommand applys the effects of the operating system command to the filter.
+func (f *EscapeFilter) dispatchOperatingSystemCommand(osc *operatingSystemCommand) error {
+	f.sequences++
+
+	if handled, err := f.handle(f.handlers.osc, osc.command, operatingSystemCommandToken(osc), f.parser.start); handled || err != nil {
+		return err
+	}
+
+	if err := processOperatingSystemCommand(f, osc); err != nil && err != invalidOperatingSystemCommand {
+		return err
+	}
+
+	return nil
+}
+
+// dispatchDeviceControlString ignores the device control string, which is not needed to filter text.
+func (f *EscapeFilter) dispatchDeviceControlString(dcs *deviceControlString) error {
+	f.sequences++
+	return nil
+}
+
+// dispatchControlString ignores the SOS, PM or APC string, which is not needed to filter text.
+func (f *EscapeFilter) dispatchControlString(cs *controlString) error {
+	f.sequences++
 	return nil
 }
 
@@ -73,3 +295,38 @@ func (f *EscapeFilter) Load(rd io.Reader) error {
 func (f *EscapeFilter) String() string {
 	return f.screen.String()
 }
+
+// Text returns the current screen content rendered with the options.
+func (f *EscapeFilter) Text(opts TextOptions) string {
+	return f.screen.text(opts, annotations(f.events, opts))
+}
+
+// annotations returns the annotations for the events enabled by the options, sorted by row.
+func annotations(events []Event, opts TextOptions) []annotation {
+	var annotations []annotation
+
+	if opts.TitleMarkers {
+		for _, tc := range eventsOf[TitleChange](events) {
+			if tc.Window {
+				annotations = append(annotations, annotation{row: tc.Row, text: "[title: " + tc.Title + "]"})
+			}
+		}
+	}
+
+	if opts.NotificationMarkers {
+		for _, n := range eventsOf[Notification](events) {
+			annotations = append(annotations, annotation{row: n.Row, text: "[notify: " + n.String() + "]"})
+		}
+	}
+
+	sortAnnotations(annotations)
+
+	return annotations
+}
+
+// sortAnnotations sorts the annotations by row, keeping the order of ones in the same row.
+func sortAnnotations(annotations []annotation) {
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].row < annotations[j].row
+	})
+}
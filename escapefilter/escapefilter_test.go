@@ -18,7 +18,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: '\u0007',
 			expected: &Screen{
-				lines: append([]string{}, lines...),
+				lines: newLines(lines...),
 				row:   2,
 				col:   18,
 			},
@@ -26,7 +26,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: '\u0008',
 			expected: &Screen{
-				lines: append([]string{}, lines...),
+				lines: newLines(lines...),
 				row:   2,
 				col:   17,
 			},
@@ -34,7 +34,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: '\u0009',
 			expected: &Screen{
-				lines: append([]string{}, lines...),
+				lines: newLines(lines...),
 				row:   2,
 				col:   25,
 			},
@@ -42,7 +42,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: '\u000A',
 			expected: &Screen{
-				lines: append([]string{}, lines...),
+				lines: newLines(lines...),
 				row:   3,
 				col:   1,
 			},
@@ -50,7 +50,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: '\u000B',
 			expected: &Screen{
-				lines: append([]string{}, lines...),
+				lines: newLines(lines...),
 				row:   3,
 				col:   18,
 			},
@@ -58,7 +58,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: '\u000D',
 			expected: &Screen{
-				lines: append([]string{}, lines...),
+				lines: newLines(lines...),
 				row:   2,
 				col:   1,
 			},
@@ -66,7 +66,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: '\u0020',
 			expected: &Screen{
-				lines: []string{lines[0], lines[1] + " "},
+				lines: newLines(lines[0], lines[1]+" "),
 				row:   2,
 				col:   19,
 			},
@@ -74,7 +74,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: 'X',
 			expected: &Screen{
-				lines: []string{lines[0], lines[1] + "X"},
+				lines: newLines(lines[0], lines[1]+"X"),
 				row:   2,
 				col:   19,
 			},
@@ -82,7 +82,7 @@ func Test_processRune(t *testing.T) {
 		{
 			r: 'あ',
 			expected: &Screen{
-				lines: []string{lines[0], lines[1] + "あ"},
+				lines: newLines(lines[0], lines[1]+"あ"),
 				row:   2,
 				col:   20,
 			},
@@ -92,7 +92,7 @@ func Test_processRune(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("r=%U", tt.r), func(t *testing.T) {
 			s := &Screen{
-				lines: append([]string{}, lines...),
+				lines: newLines(lines...),
 				row:   2,
 				col:   18,
 			}
@@ -113,6 +113,14 @@ func Test_New(t *testing.T) {
 	if s.screen == nil {
 		t.Errorf("screen should not be nil")
 	}
+
+	if !s.utf8 {
+		t.Errorf("utf8 should be true")
+	}
+
+	if s.parser == nil {
+		t.Errorf("parser should not be nil")
+	}
 }
 
 func Test_EscapeFilter_Load(t *testing.T) {
@@ -150,3 +158,165 @@ func Test_EscapeFilter_Load(t *testing.T) {
 		t.Errorf("String() differs from expected\n%v", diff.LineDiff(expected, actual))
 	}
 }
+
+func Test_EscapeFilter_Load_Charset(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b(0lqqqk\x1b(B\r",
+		"\x1b(0x\x1b(Babc\x1b(0x\x1b(B\r",
+		"\x1b)0\x0emqqqj\x0f\r",
+		"\x1b*A\x1bN#100\r",
+		"\x1b*0\x1bN\x07\x00q",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"┌───┐",
+		"│abc│",
+		"└───┘",
+		"£100",
+		"─",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if actual := filter.String(); actual != expected {
+		t.Errorf("String() differs from expected\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func Test_EscapeFilter_Load_EscapeSequence(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b=keypad\x1b>\r",
+		"\x1b%@caf\xe9\x1b%Gcaf\xc3\xa9\r",
+		"\x1b F\x1b#5plain",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"keypad",
+		"cafécafé",
+		"plain",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if actual := filter.String(); actual != expected {
+		t.Errorf("String() differs from expected\n%v", diff.LineDiff(expected, actual))
+	}
+}
+
+func Test_EscapeFilter_Load_OperatingSystemCommand(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b]0title\x07no command",
+		"\x1b]lwindow\x1b\\\x1b]Licon\x1b\\legacy",
+		"\x1b]2;cancelled\x18\x07cancelled",
+		"\x1b]2;interrupted\x1b[2Cinterrupted",
+		"\x1b]2;8-bit\u009cst",
+		"exec\x1b[\r5Cuted",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"no command",
+		"legacy",
+		"cancelled",
+		"  interrupted",
+		"st",
+		"exec uted",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if actual := filter.String(); actual != expected {
+		t.Errorf("String() differs from expected\n%v", diff.LineDiff(expected, actual))
+	}
+
+	if title := filter.Title(); title != "8-bit" {
+		t.Errorf("Title() should return %q, got %q", "8-bit", title)
+	}
+
+	if iconName := filter.IconName(); iconName != "icon" {
+		t.Errorf("IconName() should return %q, got %q", "icon", iconName)
+	}
+}
+
+func Test_EscapeFilter_Load_ISO8859_1(t *testing.T) {
+	source := "\x1b%@\x1b]2;caf\xe9\x9ccaf\xe9\x9b1Dx"
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if actual := filter.String(); actual != "cafx" {
+		t.Errorf("String() should return %q, got %q", "cafx", actual)
+	}
+
+	if title := filter.Title(); title != "café" {
+		t.Errorf("Title() should return %q, got %q", "café", title)
+	}
+}
+
+func Test_EscapeFilter_Write(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b]2;タイトル\x1b\\こんにちは\x1b[2D世界",
+		"\x1b(0lqk\x1b(B \u009b1Cdone",
+		"\x1b%@caf\xe9\x1b%G あ",
+	}, "\n")
+
+	expected := New()
+	if err := expected.Load(strings.NewReader(source)); err != nil {
+		t.Fatalf("Load() should not return error, got %v", err)
+	}
+
+	for size := 1; size <= 4; size++ {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			filter := New()
+
+			for i := 0; i < len(source); i += size {
+				end := i + size
+				if end > len(source) {
+					end = len(source)
+				}
+
+				if n, err := filter.Write([]byte(source[i:end])); n != end-i || err != nil {
+					t.Fatalf("Write() should return (%d, nil), got (%d, %v)", end-i, n, err)
+				}
+			}
+
+			if err := filter.Close(); err != nil {
+				t.Fatalf("Close() should not return error, got %v", err)
+			}
+
+			if actual := filter.String(); actual != expected.String() {
+				t.Errorf("String() should return %q, got %q", expected.String(), actual)
+			}
+
+			if diff := cmp.Diff(expected.TitleHistory(), filter.TitleHistory()); diff != "" {
+				t.Errorf("TitleHistory() differs from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_Close(t *testing.T) {
+	filter := New()
+	filter.Write([]byte("a\x1b]2;title"))
+	filter.Write([]byte("\x1b[1"))
+	filter.Write([]byte("Db\xe3\x81"))
+
+	if actual := filter.String(); actual != "b" {
+		t.Errorf("String() should return %q, got %q", "b", actual)
+	}
+
+	filter.Close()
+	filter.Write([]byte("\x1b]2;title"))
+	filter.Close()
+	filter.Write([]byte("\x07c"))
+
+	if actual := filter.String(); actual != "b��c" {
+		t.Errorf("String() should return %q, got %q", "b��c", actual)
+	}
+
+	if title := filter.Title(); title != "" {
+		t.Errorf("Title() should return %q, got %q", "", title)
+	}
+}
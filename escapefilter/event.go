@@ -0,0 +1,41 @@
+package escapefilter
+
+// Event represents what is reported by an escape code other than characters in the screen,
+// e.g. a window title change, a shell integration mark or a notification.
+type Event interface {
+	// Pos returns the cursor position when the event occurred.
+	Pos() Position
+}
+
+// Pos returns the position itself, so that types embedding Position implement Event.
+func (p Position) Pos() Position {
+	return p
+}
+
+// eventsOf returns the events of the type in order.
+func eventsOf[T Event](events []Event) []T {
+	result := []T{}
+
+	for _, e := range events {
+		if t, ok := e.(T); ok {
+			result = append(result, t)
+		}
+	}
+
+	return result
+}
+
+// addEvent records the event.
+// In the streaming mode, the events are kept only until they are converted into annotations (see Stream).
+func (f *EscapeFilter) addEvent(e Event) {
+	f.events = append(f.events, e)
+
+	if f.stream == nil {
+		f.transcript.add(f.screen, e)
+	}
+}
+
+// Events returns all the events in order of appearance in the input.
+func (f *EscapeFilter) Events() []Event {
+	return append([]Event{}, f.events...)
+}
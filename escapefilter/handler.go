@@ -0,0 +1,64 @@
+package escapefilter
+
+// SequenceHandler is called with a sequence registered to the EscapeFilter, before the built-in behavior.
+// The sequence is given as a Token, whose offsets are in the whole input of the EscapeFilter.
+// SequenceHandler returns true if it has handled the sequence, or false to fall back to the built-in behavior.
+// An error returned by SequenceHandler stops loading.
+type SequenceHandler func(s *Screen, t Token) (bool, error)
+
+// sequenceHandlers stores SequenceHandlers registered to the EscapeFilter.
+type sequenceHandlers struct {
+	// escape stores handlers for escape sequences by the intermediate and the final bytes.
+	escape map[string]SequenceHandler
+	// control stores handlers for control sequences by the intermediate and the final bytes.
+	control map[string]SequenceHandler
+	// osc stores handlers for operating system commands by the command.
+	osc map[string]SequenceHandler
+}
+
+// register registers the handler for the key to the map, or removes it if the handler is nil.
+func register(m *map[string]SequenceHandler, key string, h SequenceHandler) {
+	if h == nil {
+		delete(*m, key)
+		return
+	}
+
+	if *m == nil {
+		*m = make(map[string]SequenceHandler)
+	}
+
+	(*m)[key] = h
+}
+
+// HandleEscapeSequence registers the handler for ESC <intermediate> <final>, e.g. ("#", "8") for DECALN.
+// C1 control characters are handled as ESC Fe, e.g. U+0084 as ("", "D").
+// The handler is removed if h is nil.
+func (f *EscapeFilter) HandleEscapeSequence(intermediate string, final string, h SequenceHandler) {
+	register(&f.handlers.escape, intermediate+final, h)
+}
+
+// HandleControlSequence registers the handler for CSI ... <intermediate> <final>, e.g. (" ", "q") for DECSCUSR.
+// The handler is called regardless of the private marker and the parameters, which are available in the Token.
+// The handler is removed if h is nil.
+func (f *EscapeFilter) HandleControlSequence(intermediate string, final string, h SequenceHandler) {
+	register(&f.handlers.control, intermediate+final, h)
+}
+
+// HandleOperatingSystemCommand registers the handler for OSC <command>, e.g. "1337" or "L".
+// The handler is removed if h is nil.
+func (f *EscapeFilter) HandleOperatingSystemCommand(command string, h SequenceHandler) {
+	register(&f.handlers.osc, command, h)
+}
+
+// handle calls the handler for the key in the map with the token of the sequence starting at the offset, if registered.
+func (f *EscapeFilter) handle(m map[string]SequenceHandler, key string, t Token, start int64) (bool, error) {
+	h, ok := m[key]
+	if !ok {
+		return false, nil
+	}
+
+	t.Start = start
+	t.End = f.parser.next
+
+	return h(f.screen, t)
+}
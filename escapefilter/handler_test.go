@@ -0,0 +1,83 @@
+package escapefilter
+
+import (
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_EscapeFilter_HandleSequence(t *testing.T) {
+	source := strings.Join([]string{
+		"a\x1b[?5zb",
+		"\x1b#8c",
+		"\x1b]1337;Custom=1\x07d\x1b]1337;SetMark\x07e",
+		"f\u0084g",
+		"\x1b[1Ah",
+	}, "\n")
+
+	var actual []Token
+
+	record := func(handled bool) SequenceHandler {
+		return func(s *Screen, t Token) (bool, error) {
+			actual = append(actual, t)
+			return handled, nil
+		}
+	}
+
+	filter := New()
+	filter.HandleControlSequence("", "z", func(s *Screen, t Token) (bool, error) {
+		actual = append(actual, t)
+		s.PutRune('*')
+		return true, nil
+	})
+	filter.HandleEscapeSequence("#", "8", record(true))
+	filter.HandleEscapeSequence("", "D", record(true))
+	filter.HandleOperatingSystemCommand("1337", func(s *Screen, t Token) (bool, error) {
+		actual = append(actual, t)
+		return strings.HasPrefix(t.Data, "Custom="), nil
+	})
+	filter.HandleControlSequence("", "A", record(true))
+	filter.HandleControlSequence("", "A", nil)
+
+	if err := filter.Load(strings.NewReader(source)); err != nil {
+		t.Fatalf("Load() should not return error, got %v", err)
+	}
+
+	expected := []Token{
+		{Kind: TokenCSI, Start: 1, End: 6, Private: "?", Params: [][]int{{5}}, Final: "z"},
+		{Kind: TokenEscape, Start: 8, End: 11, Intermediate: "#", Final: "8"},
+		{Kind: TokenOSC, Start: 13, End: 29, Command: "1337", Data: "Custom=1", Terminator: "\x07"},
+		{Kind: TokenOSC, Start: 30, End: 45, Command: "1337", Data: "SetMark", Terminator: "\x07"},
+		{Kind: TokenEscape, Start: 48, End: 50, Final: "D"},
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("handled sequences differ from expected\n%s", diff)
+	}
+
+	if text := filter.String(); text != "a*b\nc\nde\nhg" {
+		t.Errorf("String() should return %q, got %q", "a*b\nc\nde\nhg", text)
+	}
+
+	if marks := filter.Marks(); len(marks) != 1 || marks[0].Kind != MarkNavigation {
+		t.Errorf("Marks() should return a navigation mark, got %v", marks)
+	}
+}
+
+func Test_EscapeFilter_HandleSequence_Error(t *testing.T) {
+	handlerError := errors.New("handler error")
+
+	filter := New()
+	filter.HandleControlSequence("", "m", func(s *Screen, t Token) (bool, error) {
+		return false, handlerError
+	})
+
+	if err := filter.Load(strings.NewReader("a\x1b[1mb")); err != handlerError {
+		t.Errorf("Load() should return %v, got %v", handlerError, err)
+	}
+
+	if text := filter.String(); text != "a" {
+		t.Errorf("String() should return %q, got %q", "a", text)
+	}
+}
@@ -0,0 +1,90 @@
+package escapefilter
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// HTML returns the screen content as a HTML <pre> element.
+// Hyperlinks are rendered as <a> elements, except for unsafe schemes rendered as plain text (see Hyperlink.isSafe).
+func (s *Screen) HTML() string {
+	return s.html(nil)
+}
+
+// html returns the screen content as a HTML <pre> element,
+// whose default colors are set by the palette (if not nil).
+func (s *Screen) html(p *Palette) string {
+	var sb strings.Builder
+
+	sb.WriteString("<pre")
+	if style := p.style(); style != "" {
+		sb.WriteString(` style="`)
+		sb.WriteString(style)
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(">")
+
+	for r := s.top + 1; r <= s.rows(); r++ {
+		if r > s.top+1 {
+			sb.WriteRune('\n')
+		}
+
+		for _, sp := range s.spans(r) {
+			if sp.link == nil || !sp.link.isSafe() {
+				sb.WriteString(html.EscapeString(sp.text))
+				continue
+			}
+
+			sb.WriteString(`<a href="`)
+			sb.WriteString(html.EscapeString(sp.link.URI))
+			sb.WriteString(`">`)
+			sb.WriteString(html.EscapeString(sp.text))
+			sb.WriteString("</a>")
+		}
+	}
+
+	sb.WriteString("</pre>\n")
+
+	return sb.String()
+}
+
+// style returns the CSS declarations for the default colors in the palette,
+// and the custom properties --color-N for the changed entries of the 256-color palette.
+func (p *Palette) style() string {
+	if p == nil {
+		return ""
+	}
+
+	var decls []string
+
+	if p.Foreground != nil {
+		decls = append(decls, "color: "+p.Foreground.String())
+	}
+
+	if p.Background != nil {
+		decls = append(decls, "background-color: "+p.Background.String())
+	}
+
+	indices := make([]int, 0, len(p.Colors))
+	for index := range p.Colors {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	for _, index := range indices {
+		decls = append(decls, fmt.Sprintf("--color-%d: %s", index, p.Colors[index].String()))
+	}
+
+	return strings.Join(decls, "; ")
+}
+
+// WriteHTML writes the current screen content to the Writer as a HTML <pre> element.
+// The default foreground and background colors changed by OSC 10 and OSC 11 are applied to the element,
+// and the palette entries changed by OSC 4 are set to the custom properties --color-N of the element for stylesheets.
+func (f *EscapeFilter) WriteHTML(w io.Writer) error {
+	_, err := io.WriteString(w, f.screen.html(&f.palette))
+	return err
+}
@@ -0,0 +1,36 @@
+package escapefilter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_EscapeFilter_WriteHTML(t *testing.T) {
+	source := strings.Join([]string{
+		"See \x1b]8;;https://example.com/?a=1&b=2\x1b\\<example>\x1b]8;;\x1b\\ for details",
+		"",
+		"\x1b]8;id=x;file:///tmp/a.txt\x07a.txt\x1b]8;;\x07 & b.txt",
+		"\x1b]8;;javascript:alert(1)\x07click\x1b]8;;\x07",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		`<pre>See <a href="https://example.com/?a=1&amp;b=2">&lt;example&gt;</a> for details`,
+		``,
+		`<a href="file:///tmp/a.txt">a.txt</a> &amp; b.txt`,
+		`click</pre>`,
+		``,
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := filter.WriteHTML(&buf); err != nil {
+		t.Fatalf("WriteHTML() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteHTML() should write %q, got %q", expected, actual)
+	}
+}
@@ -0,0 +1,186 @@
+package escapefilter
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Hyperlink represents a hyperlink set by OSC 8.
+type Hyperlink struct {
+	// ID is the id parameter, which groups cells into the same link (empty if not specified).
+	ID string
+	// URI is the target of the link.
+	URI string
+}
+
+// parseHyperlink parses the parameter of OSC 8, which is <params> ; <URI>.
+// parseHyperlink returns nil for an empty URI, which means the end of the link.
+func parseHyperlink(param string) (*Hyperlink, error) {
+	params, uri, ok := strings.Cut(param, ";")
+	if !ok {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	if uri == "" {
+		return nil, nil
+	}
+
+	link := &Hyperlink{URI: uri}
+
+	for _, kv := range strings.Split(params, ":") {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == "id" {
+			link.ID = v
+		}
+	}
+
+	return link, nil
+}
+
+// safeSchemes are the URI schemes rendered as links in HTML and Markdown.
+var safeSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"file":   true,
+	"mailto": true,
+	"ftp":    true,
+}
+
+// isSafe reports whether the URI has one of safeSchemes, so that it can be rendered as a link.
+// Other URIs (e.g. javascript:) from untrusted input are rendered as plain text.
+func (l *Hyperlink) isSafe() bool {
+	u, err := url.Parse(l.URI)
+	if err != nil {
+		return false
+	}
+
+	return safeSchemes[strings.ToLower(u.Scheme)]
+}
+
+// SetHyperlink sets the hyperlink attached to characters put after this.
+// nil ends the current hyperlink.
+func (s *Screen) SetHyperlink(link *Hyperlink) {
+	s.link = link
+}
+
+// Hyperlink returns the hyperlink attached to the cell at (row, col), or nil if none.
+func (s *Screen) Hyperlink(row int, col int) *Hyperlink {
+	i := row - s.top - 1
+	if i < 0 || len(s.links) <= i {
+		return nil
+	}
+
+	if col < 1 || len(s.links[i]) < col {
+		return nil
+	}
+
+	return s.links[i][col-1]
+}
+
+// putHyperlink attaches the current hyperlink to w cells from the current position.
+func (s *Screen) putHyperlink(w int) {
+	i := s.row - s.top - 1
+	if s.link == nil && (len(s.links) <= i || len(s.links[i]) < s.col) {
+		return
+	}
+
+	for len(s.links) <= i {
+		s.links = append(s.links, nil)
+	}
+
+	links := s.links[i]
+	for len(links) < s.col-1+w {
+		links = append(links, nil)
+	}
+
+	for i := 0; i < w; i++ {
+		links[s.col-1+i] = s.link
+	}
+
+	s.links[i] = links
+}
+
+// eraseHyperlinks detaches hyperlinks from cells in columns [from, to] in the row.
+func (s *Screen) eraseHyperlinks(row int, from int, to int) {
+	i := row - s.top - 1
+	if i < 0 || len(s.links) <= i {
+		return
+	}
+
+	links := s.links[i]
+	if to >= len(links) {
+		if from-1 < len(links) {
+			s.links[i] = links[:from-1]
+		}
+		return
+	}
+
+	for c := from; c <= to; c++ {
+		links[c-1] = nil
+	}
+}
+
+// trimHyperlinks removes hyperlinks of the rows which no longer exist.
+func (s *Screen) trimHyperlinks() {
+	if len(s.links) > len(s.lines) {
+		s.links = s.links[:len(s.lines)]
+	}
+
+	if len(s.links) == 0 {
+		s.links = nil
+	}
+}
+
+// span represents a run of characters in a line with the same hyperlink.
+type span struct {
+	text string
+	link *Hyperlink
+}
+
+// spans splits the line at the row into runs of characters with the same hyperlink.
+func (s *Screen) spans(row int) []span {
+	if i := row - s.top - 1; i < 0 || len(s.lines) <= i {
+		return nil
+	}
+
+	var spans []span
+	var sb strings.Builder
+	var link *Hyperlink
+
+	for i, r := range s.line(row) {
+		if r == wideTail {
+			continue
+		}
+
+		if l := s.Hyperlink(row, i+1); l != link && sb.Len() > 0 {
+			spans = append(spans, span{text: sb.String(), link: link})
+			sb.Reset()
+			link = l
+		} else if sb.Len() == 0 {
+			link = l
+		}
+
+		sb.WriteRune(r)
+	}
+
+	if sb.Len() > 0 {
+		spans = append(spans, span{text: sb.String(), link: link})
+	}
+
+	return spans
+}
+
+// hasHyperlinks reports whether any cell in the row has a hyperlink.
+func (s *Screen) hasHyperlinks(row int) bool {
+	i := row - s.top - 1
+	if i < 0 || len(s.links) <= i {
+		return false
+	}
+
+	for _, link := range s.links[i] {
+		if link != nil {
+			return true
+		}
+	}
+
+	return false
+}
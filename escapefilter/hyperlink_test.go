@@ -0,0 +1,202 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func Test_parseHyperlink(t *testing.T) {
+	tests := []struct {
+		param   string
+		link    *Hyperlink
+		isError bool
+	}{
+		{
+			param: ";https://example.com/",
+			link:  &Hyperlink{URI: "https://example.com/"},
+		},
+		{
+			param: "id=foo;https://example.com/",
+			link:  &Hyperlink{ID: "foo", URI: "https://example.com/"},
+		},
+		{
+			param: "bar=baz:id=foo;https://example.com/?a=b;c=d",
+			link:  &Hyperlink{ID: "foo", URI: "https://example.com/?a=b;c=d"},
+		},
+		{
+			param: ";",
+			link:  nil,
+		},
+		{
+			param: "id=foo;",
+			link:  nil,
+		},
+		{
+			param:   "https://example.com/",
+			link:    nil,
+			isError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q", tt.param), func(t *testing.T) {
+			link, err := parseHyperlink(tt.param)
+
+			if diff := cmp.Diff(tt.link, link); diff != "" {
+				t.Errorf("parseHyperlink() differs from expected\n%s", diff)
+			}
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseHyperlink() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseHyperlink() should return not error, got %#v", err)
+			}
+		})
+	}
+}
+
+func Test_Hyperlink_isSafe(t *testing.T) {
+	tests := []struct {
+		uri      string
+		expected bool
+	}{
+		{uri: "https://example.com/", expected: true},
+		{uri: "HTTP://example.com/", expected: true},
+		{uri: "file:///tmp/a.txt", expected: true},
+		{uri: "mailto:user@example.com", expected: true},
+		{uri: "ftp://example.com/a.txt", expected: true},
+		{uri: "javascript:alert(1)", expected: false},
+		{uri: "JavaScript:alert(1)", expected: false},
+		{uri: "data:text/html,<script>alert(1)</script>", expected: false},
+		{uri: "java\tscript:alert(1)", expected: false},
+		{uri: "a.txt", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("uri=%q", tt.uri), func(t *testing.T) {
+			link := &Hyperlink{URI: tt.uri}
+			if actual := link.isSafe(); actual != tt.expected {
+				t.Errorf("isSafe() should return %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Screen_Hyperlink(t *testing.T) {
+	link := &Hyperlink{URI: "https://example.com/"}
+
+	s := NewScreen()
+	for _, r := range "See " {
+		s.PutRune(r)
+	}
+	s.SetHyperlink(link)
+	for _, r := range "世界" {
+		s.PutRune(r)
+	}
+	s.SetHyperlink(nil)
+	for _, r := range "!" {
+		s.PutRune(r)
+	}
+
+	tests := []struct {
+		row  int
+		col  int
+		link *Hyperlink
+	}{
+		{row: 1, col: 4, link: nil},
+		{row: 1, col: 5, link: link},
+		{row: 1, col: 8, link: link},
+		{row: 1, col: 9, link: nil},
+		{row: 1, col: 10, link: nil},
+		{row: 2, col: 1, link: nil},
+		{row: 0, col: 0, link: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("row=%d,col=%d", tt.row, tt.col), func(t *testing.T) {
+			if link := s.Hyperlink(tt.row, tt.col); link != tt.link {
+				t.Errorf("Hyperlink() should return %v, got %v", tt.link, link)
+			}
+		})
+	}
+}
+
+func Test_Screen_Hyperlink_Erase(t *testing.T) {
+	link := &Hyperlink{URI: "https://example.com/"}
+
+	tests := []struct {
+		col      int
+		erase    func(s *Screen)
+		expected [][]*Hyperlink
+	}{
+		{col: 3, erase: (*Screen).EraseLineAfter, expected: [][]*Hyperlink{{link, link, link, link}, {link, link}}},
+		{col: 3, erase: (*Screen).EraseLineBefore, expected: [][]*Hyperlink{{link, link, link, link}, {nil, nil, nil, link}}},
+		{col: 3, erase: (*Screen).EraseLine, expected: [][]*Hyperlink{{link, link, link, link}, {}}},
+		{col: 3, erase: (*Screen).EraseScreenAfter, expected: [][]*Hyperlink{{link, link, link, link}, {link, link}}},
+		{col: 3, erase: (*Screen).EraseScreenBefore, expected: [][]*Hyperlink{{}, {nil, nil, nil, link}}},
+		{col: 3, erase: (*Screen).EraseScreen, expected: nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			s := &Screen{
+				lines: newLines("abcd", "efgh", "ijkl"),
+				row:   2,
+				col:   tt.col,
+				links: [][]*Hyperlink{{link, link, link, link}, {link, link, link, link}},
+			}
+			tt.erase(s)
+
+			if diff := cmp.Diff(tt.expected, s.links); diff != "" {
+				t.Errorf("links differs from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_Screen_spans(t *testing.T) {
+	foo := &Hyperlink{URI: "https://example.com/foo"}
+	bar := &Hyperlink{URI: "https://example.com/bar"}
+
+	s := &Screen{
+		lines: newLines("See 世界 and bar", "plain"),
+		row:   1,
+		col:   1,
+		links: [][]*Hyperlink{{nil, nil, nil, nil, foo, foo, foo, foo, nil, nil, nil, nil, nil, bar, bar, bar}},
+	}
+
+	tests := []struct {
+		row      int
+		expected []span
+	}{
+		{
+			row: 1,
+			expected: []span{
+				{text: "See "},
+				{text: "世界", link: foo},
+				{text: " and "},
+				{text: "bar", link: bar},
+			},
+		},
+		{
+			row:      2,
+			expected: []span{{text: "plain"}},
+		},
+		{
+			row:      3,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("row=%d", tt.row), func(t *testing.T) {
+			opt := cmp.AllowUnexported(span{})
+			if diff := cmp.Diff(tt.expected, s.spans(tt.row), opt); diff != "" {
+				t.Errorf("spans() differs from expected\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package escapefilter
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// DefaultInlineFileLimit is the default maximum size of an inline file in bytes.
+const DefaultInlineFileLimit = 16 << 20
+
+// InlineFile represents a file transferred by OSC 1337 File= (iTerm2), typically an inline image.
+type InlineFile struct {
+	Position
+
+	// Name is the file name (empty if not specified).
+	Name string `json:"name,omitempty"`
+	// Width is the display width like "120", "120px", "50%" or "auto" (empty if not specified).
+	Width string `json:"width,omitempty"`
+	// Height is the display height like "40", "40px", "50%" or "auto" (empty if not specified).
+	Height string `json:"height,omitempty"`
+	// Inline indicates whether the file is displayed in the screen (or downloaded otherwise).
+	Inline bool `json:"inline"`
+	// Size is the size of the decoded data in bytes.
+	Size int `json:"size"`
+	// Data is the decoded data, which is passed to the handler only.
+	Data []byte `json:"-"`
+}
+
+// InlineFileHandler is called with an inline file.
+// An error returned by InlineFileHandler stops loading.
+type InlineFileHandler func(file InlineFile) error
+
+// placeholder returns the text put to the screen instead of the image, like "[image: name 120x40]".
+// The size is omitted unless both the width and the height are specified.
+func (file *InlineFile) placeholder() string {
+	var sb strings.Builder
+
+	sb.WriteString("[image")
+
+	if file.Name != "" {
+		sb.WriteString(": ")
+		sb.WriteString(file.Name)
+	}
+
+	if file.Width != "" && file.Width != "auto" && file.Height != "" && file.Height != "auto" {
+		if file.Name == "" {
+			sb.WriteString(":")
+		}
+
+		sb.WriteString(" ")
+		sb.WriteString(file.Width)
+		sb.WriteString("x")
+		sb.WriteString(file.Height)
+	}
+
+	sb.WriteString("]")
+
+	return sb.String()
+}
+
+// inlineFileArgsLimit is the maximum length of the arguments of OSC 1337 File= accepted with the data.
+const inlineFileArgsLimit = 4096
+
+// inlineFilePayloadLimit returns the maximum length of the parameter of OSC 1337 with a file up to limit bytes.
+func inlineFilePayloadLimit(limit int) int {
+	return len("File=") + inlineFileArgsLimit + len(":") + base64.StdEncoding.EncodedLen(limit)
+}
+
+// parseInlineFile parses the parameter of OSC 1337 File=, which is File= <args> : <base64 data>.
+// <args> are <key>=<value> separated by ";", and the name is base64-encoded.
+// Files larger than limit bytes are rejected before decoding.
+func parseInlineFile(param string, limit int) (*InlineFile, error) {
+	args, data, ok := strings.Cut(strings.TrimPrefix(param, "File="), ":")
+	if !ok {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	file := &InlineFile{}
+
+	for _, kv := range strings.Split(args, ";") {
+		k, v, _ := strings.Cut(kv, "=")
+
+		switch k {
+		case "name":
+			if name, err := base64.StdEncoding.DecodeString(v); err == nil {
+				file.Name = string(name)
+			} else {
+				file.Name = v
+			}
+		case "width":
+			file.Width = v
+		case "height":
+			file.Height = v
+		case "inline":
+			file.Inline = v == "1"
+		}
+	}
+
+	if base64.StdEncoding.DecodedLen(len(data)) > limit+2 {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(decoded) > limit {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	file.Size = len(decoded)
+	file.Data = decoded
+
+	return file, nil
+}
+
+// processInlineFile records the inline file, passes it to the handler,
+// and puts the placeholder to the screen if the file is displayed.
+func (f *EscapeFilter) processInlineFile(file *InlineFile) error {
+	file.Position = f.screen.Position()
+
+	if f.inlineFileHandler != nil {
+		if err := f.inlineFileHandler(*file); err != nil {
+			return err
+		}
+	}
+
+	if file.Inline {
+		f.screen.putText(file.placeholder())
+	}
+
+	// data is not kept in the events
+	file.Data = nil
+	f.addEvent(*file)
+
+	return nil
+}
+
+// SetInlineFileHandler sets the function called with each inline file.
+// Inline files are never output as text (but as placeholders), whether the handler is set or not.
+func (f *EscapeFilter) SetInlineFileHandler(h InlineFileHandler) {
+	f.inlineFileHandler = h
+}
+
+// SetInlineFileLimit sets the maximum size of an inline file in bytes.
+// Larger files are discarded without calling the handler.
+func (f *EscapeFilter) SetInlineFileLimit(limit int) {
+	f.inlineFileLimit = limit
+}
+
+// InlineFiles returns all the inline files (without data) in order.
+func (f *EscapeFilter) InlineFiles() []InlineFile {
+	return eventsOf[InlineFile](f.events)
+}
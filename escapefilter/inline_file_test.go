@@ -0,0 +1,152 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/andreyvit/diff"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_parseInlineFile(t *testing.T) {
+	tests := []struct {
+		param   string
+		limit   int
+		file    *InlineFile
+		isError bool
+	}{
+		{
+			param: "File=name=Y2F0LnBuZw==;width=120;height=40;inline=1:SGVsbG8=",
+			limit: 16,
+			file:  &InlineFile{Name: "cat.png", Width: "120", Height: "40", Inline: true, Size: 5, Data: []byte("Hello")},
+		},
+		{
+			param: "File=name=cat.png;size=5:SGVsbG8=",
+			limit: 16,
+			file:  &InlineFile{Name: "cat.png", Size: 5, Data: []byte("Hello")},
+		},
+		{
+			param: "File=inline=1:",
+			limit: 16,
+			file:  &InlineFile{Inline: true, Data: []byte{}},
+		},
+		{param: "File=inline=1:SGVsbG8=", limit: 4, isError: true},
+		{param: "File=inline=1:!!!!", limit: 16, isError: true},
+		{param: "File=inline=1", limit: 16, isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q,limit=%d", tt.param, tt.limit), func(t *testing.T) {
+			file, err := parseInlineFile(tt.param, tt.limit)
+
+			if diff := cmp.Diff(tt.file, file); diff != "" {
+				t.Errorf("parseInlineFile() differs from expected\n%s", diff)
+			}
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseInlineFile() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseInlineFile() should return not error, got %#v", err)
+			}
+		})
+	}
+}
+
+func Test_InlineFile_placeholder(t *testing.T) {
+	tests := []struct {
+		file     InlineFile
+		expected string
+	}{
+		{file: InlineFile{Name: "cat.png", Width: "120", Height: "40"}, expected: "[image: cat.png 120x40]"},
+		{file: InlineFile{Name: "cat.png", Width: "120", Height: "auto"}, expected: "[image: cat.png]"},
+		{file: InlineFile{Name: "cat.png"}, expected: "[image: cat.png]"},
+		{file: InlineFile{Width: "50%", Height: "10px"}, expected: "[image: 50%x10px]"},
+		{file: InlineFile{}, expected: "[image]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("file=%#v", tt.file), func(t *testing.T) {
+			if actual := tt.file.placeholder(); actual != tt.expected {
+				t.Errorf("placeholder() should return %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_InlineFile(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b]1337;CurrentDir=/home/user\x07$ imgcat cat.png\x1b]1337;SetMark\x07",
+		"\x1b]1337;File=name=Y2F0LnBuZw==;width=120;height=40;inline=1:SGVsbG8=\x07",
+		"\x1b]1337;File=name=YS50eHQ=:V29ybGQ=\x1b\\$ ",
+	}, "\n")
+
+	var data []string
+
+	filter := New()
+	filter.SetInlineFileHandler(func(file InlineFile) error {
+		data = append(data, string(file.Data))
+		return nil
+	})
+
+	if err := filter.Load(strings.NewReader(source)); err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"$ imgcat cat.png",
+		"[image: cat.png 120x40]",
+		"$ ",
+	}, "\n")
+
+	if actual := filter.String(); actual != expected {
+		t.Errorf("String() differs from expected\n%s", diff.LineDiff(expected, actual))
+	}
+
+	if diff := cmp.Diff([]string{"Hello", "World"}, data); diff != "" {
+		t.Errorf("data passed to the handler differs from expected\n%s", diff)
+	}
+
+	expectedFiles := []InlineFile{
+		{Position: Position{Row: 2, Col: 1}, Name: "cat.png", Width: "120", Height: "40", Inline: true, Size: 5},
+		{Position: Position{Row: 3, Col: 1}, Name: "a.txt", Size: 5},
+	}
+
+	if diff := cmp.Diff(expectedFiles, filter.InlineFiles()); diff != "" {
+		t.Errorf("InlineFiles() differs from expected\n%s", diff)
+	}
+
+	expectedMarks := []Mark{{Position: Position{Row: 1, Col: 17}, Kind: MarkNavigation}}
+	if diff := cmp.Diff(expectedMarks, filter.Marks()); diff != "" {
+		t.Errorf("Marks() differs from expected\n%s", diff)
+	}
+
+	if dir := filter.WorkingDirectory(); dir != "/home/user" {
+		t.Errorf("WorkingDirectory() should return %q, got %q", "/home/user", dir)
+	}
+}
+
+func Test_EscapeFilter_SetInlineFileLimit_Parsing(t *testing.T) {
+	filter := New()
+	filter.SetInlineFileLimit(8)
+
+	filter.Write([]byte("\x1b]1337;File=inline=1:" + strings.Repeat("SGVsbG8g", 1<<16)))
+
+	// the payload is not accumulated beyond the limit
+	if n, max := filter.parser.data.Len(), len("1337;")+inlineFilePayloadLimit(8); n > max {
+		t.Errorf("data of the parser should be at most %d bytes, got %d", max, n)
+	}
+
+	if err := filter.Load(strings.NewReader("\x07done")); err != nil {
+		t.Fatalf("Load() should not return error, got %v", err)
+	}
+
+	if files := filter.InlineFiles(); len(files) != 0 {
+		t.Errorf("InlineFiles() should return no files, got %v", files)
+	}
+
+	if text := filter.String(); text != "done" {
+		t.Errorf("String() should return %q, got %q", "done", text)
+	}
+}
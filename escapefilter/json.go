@@ -0,0 +1,88 @@
+package escapefilter
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonSpan represents a run of characters with the same hyperlink in JSON output.
+type jsonSpan struct {
+	Text string `json:"text"`
+	URI  string `json:"uri,omitempty"`
+	ID   string `json:"id,omitempty"`
+}
+
+// jsonLine represents a line in JSON output.
+type jsonLine struct {
+	Text      string     `json:"text"`
+	Rendition string     `json:"rendition,omitempty"`
+	Spans     []jsonSpan `json:"spans,omitempty"`
+}
+
+// jsonOutput represents the entire JSON output.
+type jsonOutput struct {
+	Lines         []jsonLine        `json:"lines"`
+	Title         string            `json:"title,omitempty"`
+	IconName      string            `json:"iconName,omitempty"`
+	Titles        []TitleChange     `json:"titles,omitempty"`
+	Marks         []Mark            `json:"marks,omitempty"`
+	Directories   []DirectoryChange `json:"directories,omitempty"`
+	Notifications []Notification    `json:"notifications,omitempty"`
+	Progress      []Progress        `json:"progress,omitempty"`
+	InlineFiles   []InlineFile      `json:"inlineFiles,omitempty"`
+	Palette       *Palette          `json:"palette,omitempty"`
+}
+
+// jsonLines returns lines of the screen for JSON output.
+// Lines are output up to the cursor row like String, but without trailing spaces.
+// Spans are output only for lines with hyperlinks.
+func (s *Screen) jsonLines() []jsonLine {
+	lines := make([]jsonLine, s.rows()-s.top)
+	for i := range lines {
+		r := s.top + 1 + i
+
+		lines[i].Text = s.line(r).String()
+
+		if l := s.LineRendition(r); l != SingleWidth {
+			lines[i].Rendition = l.String()
+		}
+
+		if s.hasHyperlinks(r) {
+			for _, sp := range s.spans(r) {
+				js := jsonSpan{Text: sp.text}
+				if sp.link != nil {
+					js.URI = sp.link.URI
+					js.ID = sp.link.ID
+				}
+
+				lines[i].Spans = append(lines[i].Spans, js)
+			}
+		}
+	}
+
+	return lines
+}
+
+// WriteJSON writes the current screen content to the Writer in JSON.
+func (f *EscapeFilter) WriteJSON(w io.Writer) error {
+	out := jsonOutput{
+		Lines:         f.screen.jsonLines(),
+		Title:         f.Title(),
+		IconName:      f.IconName(),
+		Titles:        f.TitleHistory(),
+		Marks:         f.Marks(),
+		Directories:   f.DirectoryHistory(),
+		Notifications: f.Notifications(),
+		Progress:      f.ProgressHistory(),
+		InlineFiles:   f.InlineFiles(),
+	}
+
+	if p := f.Palette(); !p.isEmpty() {
+		out.Palette = &p
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(out)
+}
@@ -0,0 +1,97 @@
+package escapefilter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_EscapeFilter_WriteJSON(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b#6Wide",
+		"\x1b#3<Tall>",
+		"\x1b#4<Tall>",
+		"",
+	}, "\n")
+
+	expected := `{"lines":[` +
+		`{"text":"Wide","rendition":"double-width"},` +
+		`{"text":"<Tall>","rendition":"double-height-top"},` +
+		`{"text":"<Tall>","rendition":"double-height-bottom"},` +
+		`{"text":""}` +
+		"]}\n"
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := filter.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteJSON() should write %q, got %q", expected, actual)
+	}
+}
+
+func Test_EscapeFilter_WriteJSON_Hyperlink(t *testing.T) {
+	source := "See \x1b]8;id=ex;https://example.com/\x1b\\example\x1b]8;;\x1b\\ site\nplain"
+
+	expected := `{"lines":[` +
+		`{"text":"See example site","spans":[{"text":"See "},{"text":"example","uri":"https://example.com/","id":"ex"},{"text":" site"}]},` +
+		`{"text":"plain"}` +
+		"]}\n"
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := filter.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteJSON() should write %q, got %q", expected, actual)
+	}
+}
+
+func Test_EscapeFilter_WriteJSON_Title(t *testing.T) {
+	source := "\x1b]2;build\x07step 1\n\x1b]1;icon\x07"
+
+	expected := `{"lines":[{"text":"step 1"},{"text":""}],` +
+		`"title":"build","iconName":"icon",` +
+		`"titles":[{"row":1,"col":1,"window":true,"icon":false,"title":"build"},{"row":2,"col":1,"window":false,"icon":true,"title":"icon"}]}` +
+		"\n"
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := filter.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteJSON() should write %q, got %q", expected, actual)
+	}
+}
+
+func Test_EscapeFilter_WriteJSON_WorkingDirectory(t *testing.T) {
+	source := "\x1b]7;file://myhost/home/user/My%20Documents\x07ls"
+
+	expected := `{"lines":[{"text":"ls"}],` +
+		`"directories":[{"row":1,"col":1,"host":"myhost","path":"/home/user/My Documents"}]}` +
+		"\n"
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := filter.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteJSON() should write %q, got %q", expected, actual)
+	}
+}
@@ -0,0 +1,91 @@
+package escapefilter
+
+import (
+	"fmt"
+)
+
+// Limits represents limits of resources used by the EscapeFilter, against hostile input.
+// Zero means no limit.
+//
+// By default, exceeding a limit is clamped: rows beyond MaxRows are discarded from the top like scrolling,
+// the cursor stays within MaxCols, and characters and strings which don't fit are discarded.
+// In the strict policy, exceeding a limit is also reported as a *LimitError, which stops loading.
+type Limits struct {
+	// MaxRows is the maximum number of rows in the screen.
+	// Rows above them are discarded, or written out in the streaming mode.
+	MaxRows int
+	// MaxCols is the maximum number of columns in a line.
+	MaxCols int
+	// MaxCells is the maximum number of cells in the screen, i.e. the sum of the widths of the lines,
+	// where each line is also counted as lineCells cells for the memory of the line itself.
+	MaxCells int
+	// MaxPayload is the maximum length in bytes of the parameters of a sequence,
+	// and of the data of OSC, DCS, SOS, PM and APC.
+	MaxPayload int
+
+	// Strict reports exceeding a limit as a *LimitError.
+	Strict bool
+}
+
+// LimitKind represents the kind of a limit.
+type LimitKind string
+
+// Kinds of limits
+const (
+	LimitRows    LimitKind = "rows"
+	LimitCols    LimitKind = "cols"
+	LimitCells   LimitKind = "cells"
+	LimitPayload LimitKind = "payload"
+)
+
+// LimitError is the error reported when the input exceeds a limit in the strict policy.
+type LimitError struct {
+	// Position is the cursor position where the limit is exceeded.
+	Position
+
+	Kind LimitKind
+	// Max is the value of the limit.
+	Max int
+}
+
+// Error returns the description of the error.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit (%d) exceeded at row %d, col %d", e.Kind, e.Max, e.Row, e.Col)
+}
+
+// lineCells is the number of cells counted for a line in addition to its width,
+// which is about the memory used by a blank line (the slice header and the line break in the output)
+// in the size of cells, so that moving the cursor far down is limited as much as writing characters.
+const lineCells = 16
+
+// cellsSize returns the number of cells of the line counted against MaxCells.
+func cellsSize(line cells) int {
+	return len(line) + lineCells
+}
+
+// exceed reports that the limit is exceeded at the current position, if the policy is strict.
+// Only the first one is reported until it is taken by takeError.
+func (s *Screen) exceed(kind LimitKind, max int) {
+	if s.limits.Strict && s.err == nil {
+		s.err = &LimitError{Position: s.Position(), Kind: kind, Max: max}
+	}
+}
+
+// takeError returns the error reported by exceed and clears it (nil if none).
+func (s *Screen) takeError() error {
+	if s.err == nil {
+		return nil
+	}
+
+	err := s.err
+	s.err = nil
+
+	return err
+}
+
+// SetLimits sets the limits of resources.
+// The limits apply to the input after this, and also to the Screen passed to SequenceHandlers.
+func (f *EscapeFilter) SetLimits(l Limits) {
+	f.screen.setLimits(l)
+	f.parser.maxData = l.MaxPayload
+}
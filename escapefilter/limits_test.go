@@ -0,0 +1,149 @@
+package escapefilter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_EscapeFilter_SetLimits(t *testing.T) {
+	tests := []struct {
+		source   string
+		limits   Limits
+		expected string
+		title    string
+	}{
+		{
+			source:   "\x1b[999999999;999999999HX",
+			limits:   Limits{MaxRows: 3, MaxCols: 5},
+			expected: "\n\n    X",
+		},
+		{
+			source:   "Hello World\nこんにちは",
+			limits:   Limits{MaxCols: 5},
+			expected: "Hello\nこん",
+		},
+		{
+			source:   "Hello\nWorld\n!",
+			limits:   Limits{MaxRows: 2},
+			expected: "World\n!",
+		},
+		{
+			source:   "Hello\nWorld\n!\x1b[2J\x1b[1;1Habcdefghij",
+			limits:   Limits{MaxCells: 24},
+			expected: "abcdefgh",
+		},
+		{
+			source:   "\x1b[999999999;1Hx",
+			limits:   Limits{MaxCells: 1601},
+			expected: strings.Repeat("\n", 99) + "x",
+		},
+		{
+			source:   "abc\x1b[999999999;1Hx\x1b[1;1Hy",
+			limits:   Limits{MaxCells: 132},
+			expected: "ybc" + strings.Repeat("\n", 7) + "x",
+		},
+		{
+			source:   "\x1b#8",
+			limits:   Limits{MaxRows: 2, MaxCols: 3},
+			expected: "EEE\nEEE",
+		},
+		{
+			source:   "\x1b]2;long title\x07\x1b]2;short\x1b\\\x1b]2;long title\x1b\\",
+			limits:   Limits{MaxPayload: 8},
+			expected: "",
+			title:    "short",
+		},
+		{
+			source:   "a\x1b[1;1;1;1;1;1;1;1;1;1Db\x1b[1Dc",
+			limits:   Limits{MaxPayload: 8},
+			expected: "ac",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("source=%q,limits=%+v", tt.source, tt.limits), func(t *testing.T) {
+			filter := New()
+			filter.SetLimits(tt.limits)
+
+			if err := filter.Load(strings.NewReader(tt.source)); err != nil {
+				t.Fatalf("Load() should not return error, got %v", err)
+			}
+
+			if actual := filter.String(); actual != tt.expected {
+				t.Errorf("String() should return %q, got %q", tt.expected, actual)
+			}
+
+			if title := filter.Title(); title != tt.title {
+				t.Errorf("Title() should return %q, got %q", tt.title, title)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_SetLimits_Strict(t *testing.T) {
+	tests := []struct {
+		source   string
+		limits   Limits
+		expected *LimitError
+	}{
+		{
+			source:   "ab\x1b[999999999;999999999Hc",
+			limits:   Limits{MaxRows: 3, MaxCols: 5, Strict: true},
+			expected: &LimitError{Position: Position{Row: 1, Col: 3}, Kind: LimitRows, Max: 3},
+		},
+		{
+			source:   "ab\x1b[999Cc",
+			limits:   Limits{MaxRows: 3, MaxCols: 5, Strict: true},
+			expected: &LimitError{Position: Position{Row: 1, Col: 3}, Kind: LimitCols, Max: 5},
+		},
+		{
+			source:   "Hello World",
+			limits:   Limits{MaxCols: 5, Strict: true},
+			expected: &LimitError{Position: Position{Row: 1, Col: 6}, Kind: LimitCols, Max: 5},
+		},
+		{
+			source:   "Hello\nWorld",
+			limits:   Limits{MaxCells: 40, Strict: true},
+			expected: &LimitError{Position: Position{Row: 2, Col: 4}, Kind: LimitCells, Max: 40},
+		},
+		{
+			source:   "ab\x1b]2;long title\x07",
+			limits:   Limits{MaxPayload: 8, Strict: true},
+			expected: &LimitError{Position: Position{Row: 1, Col: 3}, Kind: LimitPayload, Max: 8},
+		},
+		{
+			source:   "Hello\nWorld\x1b]2;title\x07",
+			limits:   Limits{MaxRows: 2, MaxCols: 5, MaxCells: 42, MaxPayload: 8, Strict: true},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("source=%q,limits=%+v", tt.source, tt.limits), func(t *testing.T) {
+			filter := New()
+			filter.SetLimits(tt.limits)
+
+			err := filter.Load(strings.NewReader(tt.source))
+
+			var actual *LimitError
+			if err != nil && !errors.As(err, &actual) {
+				t.Fatalf("Load() should return *LimitError, got %#v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Errorf("Load() differs from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_LimitError_Error(t *testing.T) {
+	err := &LimitError{Position: Position{Row: 3, Col: 5}, Kind: LimitRows, Max: 2}
+
+	if actual := err.Error(); actual != "rows limit (2) exceeded at row 3, col 5" {
+		t.Errorf("Error() should return %q, got %q", "rows limit (2) exceeded at row 3, col 5", actual)
+	}
+}
@@ -0,0 +1,59 @@
+package escapefilter
+
+import (
+	"github.com/mattn/go-runewidth"
+	"strings"
+)
+
+// LineRendition represents how characters in a line are rendered.
+type LineRendition int
+
+// Line renditions set by DECSWL, DECDWL and DECDHL
+const (
+	SingleWidth LineRendition = iota
+	DoubleWidth
+	DoubleHeightTop
+	DoubleHeightBottom
+)
+
+// String returns the name of the line rendition.
+func (l LineRendition) String() string {
+	switch l {
+	case DoubleWidth:
+		return "double-width"
+	case DoubleHeightTop:
+		return "double-height-top"
+	case DoubleHeightBottom:
+		return "double-height-bottom"
+	default:
+		return "single-width"
+	}
+}
+
+// doubleWidthCols is the number of columns in a line other than single width, which is half the screen.
+const doubleWidthCols = defaultScreenCols / 2
+
+// lastCol returns the last column of the line at the row, or 0 if the line has no last column.
+// Column addressing halves on lines other than single width.
+func (s *Screen) lastCol(row int) int {
+	if s.renditions[row] == SingleWidth {
+		return 0
+	}
+
+	return doubleWidthCols
+}
+
+// expandDoubleWidth doubles the width of each character in the line by inserting spaces.
+// No spaces are appended after the last character.
+func expandDoubleWidth(line string) string {
+	var sb strings.Builder
+
+	w := 0
+	for _, r := range line {
+		sb.WriteString(strings.Repeat(" ", w))
+		sb.WriteRune(r)
+		w = runewidth.RuneWidth(r)
+	}
+
+	return sb.String()
+}
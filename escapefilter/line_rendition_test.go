@@ -0,0 +1,77 @@
+package escapefilter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_LineRendition_String(t *testing.T) {
+	tests := []struct {
+		l        LineRendition
+		expected string
+	}{
+		{l: SingleWidth, expected: "single-width"},
+		{l: DoubleWidth, expected: "double-width"},
+		{l: DoubleHeightTop, expected: "double-height-top"},
+		{l: DoubleHeightBottom, expected: "double-height-bottom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("l=%d", tt.l), func(t *testing.T) {
+			if str := tt.l.String(); str != tt.expected {
+				t.Errorf("String() should return %q, got %q", tt.expected, str)
+			}
+		})
+	}
+}
+
+func Test_expandDoubleWidth(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{line: "", expected: ""},
+		{line: "Hello", expected: "H e l l o"},
+		{line: "こんにちは", expected: "こ  ん  に  ち  は"},
+		{line: "A世B", expected: "A 世  B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("line=%q", tt.line), func(t *testing.T) {
+			if actual := expandDoubleWidth(tt.line); actual != tt.expected {
+				t.Errorf("expandDoubleWidth() should return %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_LineRendition_Columns(t *testing.T) {
+	digits := strings.Repeat("0123456789", 5)
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{source: "\x1b#6\x1b[80GX", expected: strings.Repeat(" ", 39) + "X"},
+		{source: "\x1b#3\x1b[80GX", expected: strings.Repeat(" ", 39) + "X"},
+		{source: "\x1b#6\n\x1b[80GX", expected: "\n" + strings.Repeat(" ", 79) + "X"},
+		{source: "\x1b#6" + digits, expected: digits[:39] + "9"},
+		{source: digits + "\x1b#6", expected: digits[:40]},
+		{source: "\x1b#6\x1b[40Gあ", expected: strings.Repeat(" ", 38) + "あ"},
+		{source: "\x1b#6\x1b#5\x1b[80GX", expected: strings.Repeat(" ", 79) + "X"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("source=%q", tt.source), func(t *testing.T) {
+			filter := New()
+			if err := filter.Load(strings.NewReader(tt.source)); err != nil {
+				t.Fatalf("Load() should not return error, got %v", err)
+			}
+
+			if actual := filter.String(); actual != tt.expected {
+				t.Errorf("String() should return %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
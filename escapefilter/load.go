@@ -0,0 +1,76 @@
+package escapefilter
+
+import (
+	"context"
+	"io"
+)
+
+// loadBufferSize is the size of the chunks read by LoadContext, between which cancellation is checked.
+const loadBufferSize = 32 * 1024
+
+// LoadProgress represents the progress of loading.
+type LoadProgress struct {
+	// Bytes is the number of bytes read from the Reader so far.
+	Bytes int64
+	// Sequences is the number of escape sequences, control sequences and strings processed so far.
+	Sequences int
+}
+
+// LoadProgressHandler is called with the progress of loading after each chunk of the input.
+// An error returned by LoadProgressHandler stops loading.
+type LoadProgressHandler func(p LoadProgress) error
+
+// SetLoadProgressHandler sets the function called with the progress of Load and LoadContext.
+func (f *EscapeFilter) SetLoadProgressHandler(h LoadProgressHandler) {
+	f.loadProgressHandler = h
+}
+
+// LoadContext loads contents from the Reader like Load, checking the Context between chunks of the input.
+// If the Context is done, LoadContext returns its error, leaving the input processed so far in the screen.
+// A Read blocking on the Reader is not interrupted by the Context.
+// On an error, an incomplete sequence or UTF-8 character is discarded, so that the next input starts afresh.
+func (f *EscapeFilter) LoadContext(ctx context.Context, rd io.Reader) error {
+	if err := f.load(ctx, rd); err != nil {
+		f.partial = nil
+		f.parser.reset()
+		return err
+	}
+
+	return f.endInput()
+}
+
+// load writes the contents from the Reader in chunks, checking the Context and reporting the progress.
+func (f *EscapeFilter) load(ctx context.Context, rd io.Reader) error {
+	var progress LoadProgress
+	sequences := f.sequences
+
+	buf := make([]byte, loadBufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := rd.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				return err
+			}
+
+			progress.Bytes += int64(n)
+			progress.Sequences = f.sequences - sequences
+
+			if f.loadProgressHandler != nil {
+				if err := f.loadProgressHandler(progress); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
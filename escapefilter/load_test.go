@@ -0,0 +1,93 @@
+package escapefilter
+
+import (
+	"context"
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_EscapeFilter_LoadContext(t *testing.T) {
+	source := io.MultiReader(
+		strings.NewReader("a\x1b[1mb\x1b[0m"),
+		strings.NewReader("c\x1b]0;title\x07\x1b="),
+		strings.NewReader("d\x1b["),
+		strings.NewReader("1me"),
+	)
+
+	var actual []LoadProgress
+
+	filter := New()
+	filter.SetLoadProgressHandler(func(p LoadProgress) error {
+		actual = append(actual, p)
+		return nil
+	})
+
+	if err := filter.LoadContext(context.Background(), source); err != nil {
+		t.Fatalf("LoadContext() should not return error, got %v", err)
+	}
+
+	expected := []LoadProgress{
+		{Bytes: 10, Sequences: 2},
+		{Bytes: 23, Sequences: 4},
+		{Bytes: 26, Sequences: 4},
+		{Bytes: 29, Sequences: 5},
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("progress differs from expected\n%s", diff)
+	}
+
+	if text := filter.String(); text != "abcde" {
+		t.Errorf("String() should return %q, got %q", "abcde", text)
+	}
+}
+
+func Test_EscapeFilter_LoadContext_Cancel(t *testing.T) {
+	source := io.MultiReader(
+		strings.NewReader("abc\x1b[1"),
+		strings.NewReader("def"),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	filter := New()
+	filter.SetLoadProgressHandler(func(p LoadProgress) error {
+		cancel()
+		return nil
+	})
+
+	if err := filter.LoadContext(ctx, source); err != context.Canceled {
+		t.Errorf("LoadContext() should return %v, got %v", context.Canceled, err)
+	}
+
+	if text := filter.String(); text != "abc" {
+		t.Errorf("String() should return %q, got %q", "abc", text)
+	}
+
+	// the aborted sequence doesn't continue in the next input
+	filter.SetLoadProgressHandler(nil)
+	if err := filter.Load(strings.NewReader("Dd")); err != nil {
+		t.Fatalf("Load() should not return error, got %v", err)
+	}
+
+	if text := filter.String(); text != "abcDd" {
+		t.Errorf("String() should return %q, got %q", "abcDd", text)
+	}
+}
+
+func Test_EscapeFilter_LoadContext_Error(t *testing.T) {
+	handlerError := errors.New("handler error")
+
+	filter := New()
+	filter.SetLoadProgressHandler(func(p LoadProgress) error {
+		return handlerError
+	})
+
+	if err := filter.Load(strings.NewReader("abc")); err != handlerError {
+		t.Errorf("Load() should return %v, got %v", handlerError, err)
+	}
+}
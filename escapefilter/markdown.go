@@ -0,0 +1,65 @@
+package escapefilter
+
+import (
+	"io"
+	"strings"
+)
+
+// markdownEscaper escapes characters which have special meanings in Markdown.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`#`, `\#`,
+	`|`, `\|`,
+)
+
+// markdownURIEscaper escapes characters which terminate the link destination in Markdown.
+var markdownURIEscaper = strings.NewReplacer(
+	` `, `%20`,
+	`(`, `%28`,
+	`)`, `%29`,
+)
+
+// Markdown returns the screen content in Markdown.
+// Each line ends with a hard line break, and hyperlinks are rendered as inline links
+// (except for unsafe schemes rendered as plain text, see Hyperlink.isSafe).
+func (s *Screen) Markdown() string {
+	var sb strings.Builder
+
+	for r := s.top + 1; r <= s.rows(); r++ {
+		if r > s.top+1 {
+			sb.WriteString("  \n")
+		}
+
+		for _, sp := range s.spans(r) {
+			if sp.link == nil || !sp.link.isSafe() {
+				sb.WriteString(markdownEscaper.Replace(sp.text))
+				continue
+			}
+
+			sb.WriteRune('[')
+			sb.WriteString(markdownEscaper.Replace(sp.text))
+			sb.WriteString("](")
+			sb.WriteString(markdownURIEscaper.Replace(sp.link.URI))
+			sb.WriteRune(')')
+		}
+	}
+
+	if s.rows() > s.top {
+		sb.WriteRune('\n')
+	}
+
+	return sb.String()
+}
+
+// WriteMarkdown writes the current screen content to the Writer in Markdown.
+func (f *EscapeFilter) WriteMarkdown(w io.Writer) error {
+	_, err := io.WriteString(w, f.screen.Markdown())
+	return err
+}
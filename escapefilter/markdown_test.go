@@ -0,0 +1,36 @@
+package escapefilter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_EscapeFilter_WriteMarkdown(t *testing.T) {
+	source := strings.Join([]string{
+		"See \x1b]8;;https://example.com/a (b)\x1b\\[example]\x1b]8;;\x1b\\ for *details*",
+		"",
+		"\x1b]8;id=x;file:///tmp/a_b.txt\x07a_b.txt\x1b]8;;\x07",
+		"\x1b]8;;javascript:alert(1)\x07click\x1b]8;;\x07",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		`See [\[example\]](https://example.com/a%20%28b%29) for \*details\*  `,
+		`  `,
+		`[a\_b.txt](file:///tmp/a_b.txt)  `,
+		`click`,
+		``,
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := filter.WriteMarkdown(&buf); err != nil {
+		t.Fatalf("WriteMarkdown() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteMarkdown() should write %q, got %q", expected, actual)
+	}
+}
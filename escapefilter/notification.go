@@ -0,0 +1,138 @@
+package escapefilter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Notification represents a desktop notification sent by OSC 9 or OSC 777.
+type Notification struct {
+	Position
+
+	// Title is the title of the notification (empty for OSC 9, which has the body only).
+	Title string `json:"title,omitempty"`
+	// Body is the text of the notification.
+	Body string `json:"body"`
+}
+
+// String returns the text of the notification like "title: body".
+func (n Notification) String() string {
+	if n.Title == "" {
+		return n.Body
+	}
+
+	return n.Title + ": " + n.Body
+}
+
+// ProgressState represents the state of a progress bar.
+type ProgressState int
+
+// Progress states set by OSC 9 ; 4 (ConEmu, Windows Terminal)
+const (
+	ProgressHidden ProgressState = iota
+	ProgressNormal
+	ProgressError
+	ProgressIndeterminate
+	ProgressPaused
+)
+
+// String returns the name of the progress state.
+func (p ProgressState) String() string {
+	switch p {
+	case ProgressNormal:
+		return "normal"
+	case ProgressError:
+		return "error"
+	case ProgressIndeterminate:
+		return "indeterminate"
+	case ProgressPaused:
+		return "paused"
+	default:
+		return "hidden"
+	}
+}
+
+// MarshalText returns the name of the progress state, which is used in JSON output.
+func (p ProgressState) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// Progress represents a change of the progress bar reported by OSC 9 ; 4.
+type Progress struct {
+	Position
+
+	State ProgressState `json:"state"`
+	// Percent is the progress in percent, from 0 to 100.
+	Percent int `json:"percent"`
+}
+
+// isConEmuCommand reports whether the parameter of OSC 9 is a ConEmu command like "4;1;50",
+// rather than the text of a notification.
+func isConEmuCommand(param string) bool {
+	command, _, _ := strings.Cut(param, ";")
+	if command == "" {
+		return false
+	}
+
+	_, err := strconv.Atoi(command)
+	return err == nil
+}
+
+// parseProgress parses the parameter of OSC 9 ; 4, which is 4 ; <state> ; <percent>.
+// Both state and percent default to 0.
+func parseProgress(pos Position, param string) (*Progress, error) {
+	params := strings.Split(param, ";")
+	if params[0] != "4" || len(params) > 3 {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	values := [2]int{}
+	for i, p := range params[1:] {
+		if p == "" {
+			continue
+		}
+
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return nil, invalidOperatingSystemCommand
+		}
+
+		values[i] = v
+	}
+
+	if values[0] > int(ProgressPaused) || values[1] > 100 {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	return &Progress{Position: pos, State: ProgressState(values[0]), Percent: values[1]}, nil
+}
+
+// parseNotification parses the parameter of OSC 9 (<body>) or OSC 777 (notify ; <title> ; <body>).
+// parseNotification returns nil for other commands of OSC 777.
+func parseNotification(pos Position, param string, urxvt bool) (*Notification, error) {
+	if !urxvt {
+		return &Notification{Position: pos, Body: param}, nil
+	}
+
+	command, rest, _ := strings.Cut(param, ";")
+	if command != "notify" {
+		return nil, nil
+	}
+
+	title, body, ok := strings.Cut(rest, ";")
+	if !ok {
+		return nil, invalidOperatingSystemCommand
+	}
+
+	return &Notification{Position: pos, Title: title, Body: body}, nil
+}
+
+// Notifications returns all the desktop notifications in order.
+func (f *EscapeFilter) Notifications() []Notification {
+	return eventsOf[Notification](f.events)
+}
+
+// ProgressHistory returns all the changes of the progress bar in order.
+func (f *EscapeFilter) ProgressHistory() []Progress {
+	return eventsOf[Progress](f.events)
+}
@@ -0,0 +1,127 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/andreyvit/diff"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_parseProgress(t *testing.T) {
+	tests := []struct {
+		param    string
+		expected *Progress
+		isError  bool
+	}{
+		{param: "4;1;50", expected: &Progress{State: ProgressNormal, Percent: 50}},
+		{param: "4;3", expected: &Progress{State: ProgressIndeterminate}},
+		{param: "4;0;", expected: &Progress{State: ProgressHidden}},
+		{param: "4", expected: &Progress{State: ProgressHidden}},
+		{param: "4;5;50", isError: true},
+		{param: "4;1;101", isError: true},
+		{param: "4;1;-1", isError: true},
+		{param: "4;a;50", isError: true},
+		{param: "4;1;50;0", isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q", tt.param), func(t *testing.T) {
+			actual, err := parseProgress(Position{}, tt.param)
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseProgress() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseProgress() should return not error, got %#v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Errorf("parseProgress() differs from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_parseNotification(t *testing.T) {
+	tests := []struct {
+		param    string
+		urxvt    bool
+		expected *Notification
+		isError  bool
+	}{
+		{param: "build finished", urxvt: false, expected: &Notification{Body: "build finished"}},
+		{param: "a;b", urxvt: false, expected: &Notification{Body: "a;b"}},
+		{param: "notify;make;build finished", urxvt: true, expected: &Notification{Title: "make", Body: "build finished"}},
+		{param: "notify;make;a;b", urxvt: true, expected: &Notification{Title: "make", Body: "a;b"}},
+		{param: "notify;make", urxvt: true, isError: true},
+		{param: "preexec", urxvt: true, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q,urxvt=%t", tt.param, tt.urxvt), func(t *testing.T) {
+			actual, err := parseNotification(Position{}, tt.param, tt.urxvt)
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseNotification() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseNotification() should return not error, got %#v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, actual); diff != "" {
+				t.Errorf("parseNotification() differs from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_Notifications(t *testing.T) {
+	source := strings.Join([]string{
+		"$ make\x1b]9;4;3\x07",
+		"compiling\x1b]9;4;1;50\x07",
+		"linking\x1b]9;4;0\x07\x1b]9;1;100\x07",
+		"\x1b]9;build finished\x07\x1b]777;notify;make;done\x1b\\$ ",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	expectedNotifications := []Notification{
+		{Position: Position{Row: 4, Col: 1}, Body: "build finished"},
+		{Position: Position{Row: 4, Col: 1}, Title: "make", Body: "done"},
+	}
+
+	if diff := cmp.Diff(expectedNotifications, filter.Notifications()); diff != "" {
+		t.Errorf("Notifications() differs from expected\n%s", diff)
+	}
+
+	expectedProgress := []Progress{
+		{Position: Position{Row: 1, Col: 7}, State: ProgressIndeterminate},
+		{Position: Position{Row: 2, Col: 10}, State: ProgressNormal, Percent: 50},
+		{Position: Position{Row: 3, Col: 8}, State: ProgressHidden},
+	}
+
+	if diff := cmp.Diff(expectedProgress, filter.ProgressHistory()); diff != "" {
+		t.Errorf("ProgressHistory() differs from expected\n%s", diff)
+	}
+
+	if n := len(filter.Events()); n != 5 {
+		t.Errorf("Events() should return %d events, got %d", 5, n)
+	}
+
+	expected := strings.Join([]string{
+		"$ make",
+		"compiling",
+		"linking",
+		"[notify: build finished]",
+		"[notify: make: done]",
+		"$ ",
+	}, "\n")
+
+	if actual := filter.Text(TextOptions{NotificationMarkers: true}); actual != expected {
+		t.Errorf("Text() differs from expected\n%s", diff.LineDiff(expected, actual))
+	}
+}
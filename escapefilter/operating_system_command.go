@@ -1,9 +1,9 @@
 package escapefilter
 
 import (
-	"bufio"
 	"errors"
 	"strings"
+	"unicode/utf8"
 )
 
 // operatingSystemCommand represents operating system command, which is OSC <command> ; <param>* <final>.
@@ -13,76 +13,198 @@ type operatingSystemCommand struct {
 	final        string
 }
 
-// String returns the string representation of the control sequence.
+// String returns the string representation of the operating system command.
 func (c *operatingSystemCommand) String() string {
 	var sb strings.Builder
 
 	sb.WriteString("\u001B]")
 	sb.WriteString(c.command)
-	sb.WriteString(";")
+	if c.isNumeric() {
+		sb.WriteString(";")
+	}
 	sb.WriteString(c.param)
 	sb.WriteString(c.final)
 
 	return sb.String()
 }
 
+// isNumeric reports whether the command is digits (or a legacy command like "L" otherwise).
+func (c *operatingSystemCommand) isNumeric() bool {
+	return c.command != "" && '\u0030' <= c.command[0] && c.command[0] <= '\u0039'
+}
+
 // invalidOperatingSystemCommand represents the error of parsing operating system command.
 var invalidOperatingSystemCommand = errors.New("invalid operating system command")
 
-// readOperatingSystemCommand reads a control sequence from the Reader.
-func readOperatingSystemCommand(rd *bufio.Reader) (*operatingSystemCommand, error) {
+// newOperatingSystemCommand parses the string of an operating system command (without the terminator).
+// The command is either digits followed by ";" (e.g. "0;title") or a character other than digits (e.g. "Ltitle").
+func newOperatingSystemCommand(str string) (*operatingSystemCommand, error) {
 	osc := &operatingSystemCommand{}
 
-	const (
-		COMMAND = iota
-		PARAMETER
-		ST
-		END
-	)
-
-	for state := COMMAND; state != END; {
-		r, s, err := rd.ReadRune()
-		if s == 0 {
-			return osc, err
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return r < '\u0030' || '\u0039' < r
+	})
+
+	switch {
+	case i < 0:
+		// digits only (no parameters)
+		osc.command = str
+	case i > 0:
+		if str[i] != ';' {
+			return nil, invalidOperatingSystemCommand
+		}
+
+		osc.command = str[:i]
+		osc.param = str[i+1:]
+	case str[0] == ';':
+		return nil, invalidOperatingSystemCommand
+	default:
+		_, size := utf8.DecodeRuneInString(str)
+		osc.command = str[:size]
+		osc.param = str[size:]
+	}
+
+	return osc, nil
+}
+
+// oscLimit returns the maximum length of the data of an OSC with the command, which is <command> ; <param>,
+// so that payloads larger than the limits of the handlers are discarded while being parsed (0 if unlimited).
+func (f *EscapeFilter) oscLimit(command string) int {
+	switch command {
+	case "52":
+		return len(command) + len(";") + clipboardPayloadLimit(f.clipboardLimit)
+	case "1337":
+		return len(command) + len(";") + inlineFilePayloadLimit(f.inlineFileLimit)
+	default:
+		return 0
+	}
+}
+
+// processOperatingSystemCommand applys the effects of the operating system command to the filter.
+func processOperatingSystemCommand(f *EscapeFilter, osc *operatingSystemCommand) error {
+	s := f.screen
+
+	switch osc.command {
+	case "0": // icon name and window title
+		f.addEvent(f.titles.set(s.Position(), true, true, osc.param))
+	case "1": // icon name
+		f.addEvent(f.titles.set(s.Position(), false, true, osc.param))
+	case "2": // window title
+		f.addEvent(f.titles.set(s.Position(), true, false, osc.param))
+	case "4": // change color
+		return f.palette.setColors(osc.param)
+	case "7": // working directory
+		host, path, err := parseWorkingDirectory(osc.param)
+		if err != nil {
+			return err
+		}
+
+		f.changeDirectory(host, path)
+	case "8": // hyperlink
+		link, err := parseHyperlink(osc.param)
+		if err != nil {
+			return err
 		}
 
-		switch state {
-		case COMMAND:
-			switch {
-			case '\u0030' <= r && r <= '\u0039':
-				osc.command += string(r)
-			case r == ';':
-				state = PARAMETER
-			default:
-				rd.UnreadRune()
-				return osc, invalidOperatingSystemCommand
+		s.SetHyperlink(link)
+	case "9": // notification (iTerm2) or ConEmu command
+		if isConEmuCommand(osc.param) {
+			// only progress is supported in ConEmu commands
+			if !strings.HasPrefix(osc.param, "4;") && osc.param != "4" {
+				break
 			}
-		case PARAMETER:
-			switch r {
-			case '\u0007': // BEL
-				osc.final += string(r)
-				state = END
-			case '\u001B': // ESC
-				state = ST
-			default:
-				osc.param += string(r)
+
+			p, err := parseProgress(s.Position(), osc.param)
+			if err != nil {
+				return err
 			}
-		case ST:
-			if r == '\\' {
-				osc.final += "\u001B" + string(r)
-				state = END
-			} else {
-				osc.param += "\u001B" + string(r)
-				state = PARAMETER
+
+			f.addEvent(*p)
+			break
+		}
+
+		n, err := parseNotification(s.Position(), osc.param, false)
+		if err != nil {
+			return err
+		}
+
+		f.addEvent(*n)
+	case "10": // change foreground color
+		return f.palette.setDynamicColors(10, osc.param)
+	case "11": // change background color
+		return f.palette.setDynamicColors(11, osc.param)
+	case "52": // clipboard
+		c, err := parseClipboard(osc.param, f.clipboardLimit)
+		if err != nil {
+			return err
+		}
+
+		if c != nil && f.clipboardHandler != nil {
+			c.Position = s.Position()
+			if err := f.clipboardHandler(*c); err != nil {
+				return err
 			}
 		}
-	}
+	case "104": // reset color
+		return f.palette.resetColors(osc.param)
+	case "110": // reset foreground color
+		f.palette.Foreground = nil
+	case "111": // reset background color
+		f.palette.Background = nil
+	case "133", "633": // shell integration (FinalTerm, VS Code)
+		if name, value, ok := parseVSCodeProperty(osc.param); ok && osc.command == "633" {
+			if name == "Cwd" && strings.HasPrefix(value, "/") {
+				f.changeDirectory("", value)
+			}
+			break
+		}
 
-	return osc, nil
-}
+		mark, err := parseMark(s.Position(), osc.param, osc.command == "633")
+		if err != nil {
+			return err
+		}
+
+		if mark != nil {
+			f.addEvent(*mark)
+		}
+	case "1337": // proprietary commands (iTerm2)
+		key, value, _ := strings.Cut(osc.param, "=")
+
+		switch key {
+		case "SetMark":
+			f.addEvent(Mark{Position: s.Position(), Kind: MarkNavigation})
+		case "CurrentDir":
+			if !strings.HasPrefix(value, "/") {
+				return invalidOperatingSystemCommand
+			}
+
+			f.changeDirectory("", value)
+		case "File":
+			file, err := parseInlineFile(osc.param, f.inlineFileLimit)
+			if err != nil {
+				return err
+			}
+
+			return f.processInlineFile(file)
+		default:
+			// unsupported, just ignore
+		}
+	case "L": // icon name (legacy)
+		f.addEvent(f.titles.set(s.Position(), false, true, osc.param))
+	case "l": // window title (legacy)
+		f.addEvent(f.titles.set(s.Position(), true, false, osc.param))
+	case "777": // notification (urxvt)
+		n, err := parseNotification(s.Position(), osc.param, true)
+		if err != nil {
+			return err
+		}
+
+		if n != nil {
+			f.addEvent(*n)
+		}
+	default:
+		// unsupported, just ignore
+	}
 
-// processOperatingSystemCommand applys the effects of the control sequence to screen.
-func processOperatingSystemCommand(s *Screen, osc *operatingSystemCommand) error {
-	// ignore all
 	return nil
 }
@@ -0,0 +1,480 @@
+package escapefilter
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// parserHandler is called by the parser for each character and sequence in the input.
+type parserHandler interface {
+	// print is called with a graphic character.
+	print(r rune) error
+	// execute is called with a C0 or C1 control character (including those inside sequences).
+	execute(r rune) error
+	// dispatchEscapeSequence is called with an escape sequence other than CSI, OSC, DCS, SOS, PM and APC.
+	dispatchEscapeSequence(es *escapeSequence) error
+	// dispatchControlSequence is called with a control sequence.
+	dispatchControlSequence(cs *controlSequence) error
+	// dispatchOperatingSystemCommand is called with a terminated operating system command.
+	dispatchOperatingSystemCommand(osc *operatingSystemCommand) error
+	// dispatchDeviceControlString is called with a terminated device control string.
+	dispatchDeviceControlString(dcs *deviceControlString) error
+	// dispatchControlString is called with a terminated SOS, PM or APC string.
+	dispatchControlString(cs *controlString) error
+}
+
+// parserState represents a state of the parser.
+type parserState int
+
+// States of the parser (see https://vt100.net/emu/dec_ansi_parser)
+const (
+	stateGround parserState = iota
+	stateEscape
+	stateEscapeIntermediate
+	stateCSIEntry
+	stateCSIParam
+	stateCSIIntermediate
+	stateCSIIgnore
+	stateDCSEntry
+	stateDCSParam
+	stateDCSIntermediate
+	stateDCSPassthrough
+	stateDCSIgnore
+	stateOSCString
+	stateSOSPMAPCString
+
+	stateCount
+)
+
+// isString reports whether the state is in a string terminated by ST.
+func (st parserState) isString() bool {
+	return st == stateDCSPassthrough || st == stateOSCString || st == stateSOSPMAPCString
+}
+
+// parserAction represents an action of the parser on a transition.
+type parserAction int
+
+// Actions of the parser
+const (
+	actionNone parserAction = iota
+	actionIgnore
+	actionPrint
+	actionExecute
+	actionCollect
+	actionParam
+	actionEscapeDispatch
+	actionCSIDispatch
+	actionIntroduce
+	actionHook
+	actionPut
+	actionAbort
+)
+
+// transition represents what the parser does with a character in a state.
+type transition struct {
+	action parserAction
+	// next is the next state (the state doesn't change if hasNext is false).
+	next    parserState
+	hasNext bool
+}
+
+// transitions is the table of transitions for characters U+0000-U+009F by state.
+// Characters U+00A0 and above are handled by highTransitions.
+var transitions [stateCount][0xA0]transition
+
+// highTransitions is the table of transitions for characters U+00A0 and above by state.
+// They are printed in the ground state and are parts of strings,
+// while they abort sequences otherwise and are printed in the ground state.
+var highTransitions [stateCount]transition
+
+// on sets the transition for characters [from, to] in the state.
+func on(st parserState, from rune, to rune, action parserAction) {
+	for r := from; r <= to; r++ {
+		transitions[st][r] = transition{action: action}
+	}
+}
+
+// onTo sets the transition to the next state for characters [from, to] in the state.
+func onTo(st parserState, from rune, to rune, action parserAction, next parserState) {
+	for r := from; r <= to; r++ {
+		transitions[st][r] = transition{action: action, next: next, hasNext: true}
+	}
+}
+
+// onC0 sets the transition for C0 controls except CAN, SUB and ESC in the state.
+func onC0(st parserState, action parserAction) {
+	on(st, '\u0000', '\u0017', action)
+	on(st, '\u0019', '\u0019', action)
+	on(st, '\u001C', '\u001F', action)
+}
+
+func init() {
+	for st := parserState(0); st < stateCount; st++ {
+		switch st {
+		case stateGround:
+			onC0(st, actionExecute)
+			on(st, ' ', '\u007F', actionPrint)
+		case stateEscape:
+			onC0(st, actionExecute)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, ' ', '/', actionCollect, stateEscapeIntermediate)
+			onTo(st, '0', '~', actionEscapeDispatch, stateGround)
+			onTo(st, '[', '[', actionNone, stateCSIEntry)
+			onTo(st, ']', ']', actionNone, stateOSCString)
+			onTo(st, 'P', 'P', actionNone, stateDCSEntry)
+			onTo(st, 'X', 'X', actionIntroduce, stateSOSPMAPCString)
+			onTo(st, '^', '_', actionIntroduce, stateSOSPMAPCString)
+		case stateEscapeIntermediate:
+			onC0(st, actionExecute)
+			on(st, ' ', '/', actionCollect)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, '0', '~', actionEscapeDispatch, stateGround)
+		case stateCSIEntry:
+			onC0(st, actionExecute)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, ' ', '/', actionCollect, stateCSIIntermediate)
+			// sub-parameters separated by ":" are accepted as parameters
+			onTo(st, '0', '?', actionParam, stateCSIParam)
+			onTo(st, '@', '~', actionCSIDispatch, stateGround)
+		case stateCSIParam:
+			onC0(st, actionExecute)
+			on(st, '0', ';', actionParam)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, '<', '?', actionNone, stateCSIIgnore)
+			onTo(st, ' ', '/', actionCollect, stateCSIIntermediate)
+			onTo(st, '@', '~', actionCSIDispatch, stateGround)
+		case stateCSIIntermediate:
+			onC0(st, actionExecute)
+			on(st, ' ', '/', actionCollect)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, '0', '?', actionNone, stateCSIIgnore)
+			onTo(st, '@', '~', actionCSIDispatch, stateGround)
+		case stateCSIIgnore:
+			onC0(st, actionExecute)
+			on(st, ' ', '?', actionIgnore)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, '@', '~', actionNone, stateGround)
+		case stateDCSEntry:
+			onC0(st, actionIgnore)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, ' ', '/', actionCollect, stateDCSIntermediate)
+			onTo(st, '0', '?', actionParam, stateDCSParam)
+			onTo(st, '@', '~', actionHook, stateDCSPassthrough)
+		case stateDCSParam:
+			onC0(st, actionIgnore)
+			on(st, '0', ';', actionParam)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, '<', '?', actionNone, stateDCSIgnore)
+			onTo(st, ' ', '/', actionCollect, stateDCSIntermediate)
+			onTo(st, '@', '~', actionHook, stateDCSPassthrough)
+		case stateDCSIntermediate:
+			onC0(st, actionIgnore)
+			on(st, ' ', '/', actionCollect)
+			on(st, '\u007F', '\u007F', actionIgnore)
+			onTo(st, '0', '?', actionNone, stateDCSIgnore)
+			onTo(st, '@', '~', actionHook, stateDCSPassthrough)
+		case stateDCSPassthrough:
+			onC0(st, actionPut)
+			on(st, ' ', '~', actionPut)
+			on(st, '\u007F', '\u007F', actionIgnore)
+		case stateDCSIgnore:
+			onC0(st, actionIgnore)
+			on(st, ' ', '\u007F', actionIgnore)
+		case stateOSCString:
+			onC0(st, actionIgnore)
+			on(st, ' ', '\u007F', actionPut)
+			// BEL terminates OSC like xterm
+			onTo(st, '\u0007', '\u0007', actionNone, stateGround)
+		case stateSOSPMAPCString:
+			onC0(st, actionIgnore)
+			on(st, ' ', '\u007F', actionPut)
+		}
+
+		// anywhere
+		onTo(st, '\u0018', '\u0018', actionExecute, stateGround)
+		onTo(st, '\u001A', '\u001A', actionExecute, stateGround)
+		onTo(st, '\u001B', '\u001B', actionNone, stateEscape)
+		onTo(st, '\u0080', '\u009F', actionExecute, stateGround)
+		onTo(st, '\u0090', '\u0090', actionNone, stateDCSEntry)
+		onTo(st, '\u0098', '\u0098', actionIntroduce, stateSOSPMAPCString)
+		onTo(st, '\u009B', '\u009B', actionNone, stateCSIEntry)
+		onTo(st, '\u009C', '\u009C', actionNone, stateGround)
+		onTo(st, '\u009D', '\u009D', actionNone, stateOSCString)
+		onTo(st, '\u009E', '\u009F', actionIntroduce, stateSOSPMAPCString)
+
+		switch {
+		case st == stateGround:
+			highTransitions[st] = transition{action: actionPrint}
+		case st.isString():
+			highTransitions[st] = transition{action: actionPut}
+		case st == stateCSIIgnore || st == stateDCSIgnore:
+			highTransitions[st] = transition{action: actionIgnore}
+		default:
+			highTransitions[st] = transition{action: actionAbort, next: stateGround, hasNext: true}
+		}
+	}
+}
+
+// parser splits the input into characters and sequences, modeled on the DEC ANSI parser by Paul Williams.
+// Control characters inside sequences are executed without interrupting them,
+// and CAN, SUB and C1 controls abort sequences (and strings).
+type parser struct {
+	handler parserHandler
+	state   parserState
+
+	intermediate strings.Builder
+	param        strings.Builder
+	final        string
+	data         strings.Builder
+
+	// introducer is the final character of the introducer of SOS ("X"), PM ("^") or APC ("_").
+	introducer string
+
+	// pending is the string waiting for "\" after ESC, which completes ST (nil if none).
+	pending func(final string) error
+
+	// offset is the byte offset of the character being processed.
+	offset int64
+	// next is the byte offset of the character after the one being processed.
+	next int64
+	// start is the byte offset where the sequence being parsed starts.
+	start int64
+
+	// maxData is the maximum length in bytes of the parameters and the data of a sequence (0 if unlimited).
+	maxData int
+	// overflow indicates that the sequence being parsed exceeded maxData, so that it is discarded.
+	overflow bool
+
+	// oscLimit returns the maximum length in bytes of the data of an OSC with the command (0 if unlimited),
+	// so that payloads to be rejected anyway are not accumulated (nil if none).
+	oscLimit func(command string) int
+	// limit is the maximum length of the data of the OSC being parsed given by oscLimit (0 if unlimited).
+	limit int
+	// commandEnd indicates that the command of the OSC being parsed has ended with ";".
+	commandEnd bool
+}
+
+// dataTooLong is returned when the sequence being parsed first exceeds maxData.
+var dataTooLong = errors.New("data too long")
+
+// fits reports whether the length fits in maxData, and marks the sequence as overflow otherwise.
+// fits returns dataTooLong when the sequence first overflows.
+func (p *parser) fits(n int) (bool, error) {
+	if p.maxData <= 0 || n <= p.maxData {
+		return true, nil
+	}
+
+	if p.overflow {
+		return false, nil
+	}
+
+	p.overflow = true
+	return false, dataTooLong
+}
+
+// newParser returns a new parser calling the handler.
+func newParser(h parserHandler) *parser {
+	return &parser{handler: h}
+}
+
+// reset discards the sequence being parsed and returns to the ground state.
+func (p *parser) reset() {
+	p.state = stateGround
+	p.clear()
+	p.pending = nil
+}
+
+// clear clears the intermediates, the parameters and the data of the sequence.
+func (p *parser) clear() {
+	p.intermediate.Reset()
+	p.param.Reset()
+	p.final = ""
+	p.data.Reset()
+	p.overflow = false
+	p.limit = 0
+	p.commandEnd = false
+}
+
+// advance processes a character, which is size bytes in the input.
+func (p *parser) advance(r rune, size int) error {
+	p.offset = p.next
+	p.next += int64(size)
+
+	return p.step(r)
+}
+
+// step processes a character by a transition.
+func (p *parser) step(r rune) error {
+	var t transition
+	if r < 0xA0 {
+		t = transitions[p.state][r]
+	} else {
+		t = highTransitions[p.state]
+	}
+
+	if t.hasNext && p.state.isString() {
+		if err := p.terminate(r); err != nil {
+			return err
+		}
+	}
+
+	// the transition is still needed after the sequence overflows
+	err := p.act(t.action, r)
+	if err != nil && err != dataTooLong {
+		return err
+	}
+
+	if !t.hasNext {
+		return err
+	}
+
+	from := p.state
+	p.state = t.next
+
+	switch t.next {
+	case stateEscape:
+		// ESC in a string may be the start of ST, which completes the pending string
+		if !from.isString() {
+			p.pending = nil
+		}
+		p.start = p.offset
+		p.clear()
+	case stateCSIEntry, stateDCSEntry, stateOSCString, stateSOSPMAPCString:
+		// 8-bit introducers start sequences by themselves
+		if from != stateEscape {
+			p.start = p.offset
+		}
+		p.pending = nil
+		p.clear()
+	}
+
+	// the aborting character is processed again in the ground state
+	if t.action == actionAbort {
+		return p.step(r)
+	}
+
+	return err
+}
+
+// terminate completes the string being parsed when leaving the string state by the character.
+// BEL (OSC only) and 8-bit ST complete it, ESC makes it pending until "\", and others discard it.
+func (p *parser) terminate(r rune) error {
+	var dispatch func(final string) error
+
+	switch {
+	case p.overflow:
+		dispatch = func(final string) error {
+			return nil // too long, just ignore
+		}
+	case p.state == stateOSCString:
+		osc, err := newOperatingSystemCommand(p.data.String())
+		dispatch = func(final string) error {
+			if err != nil {
+				return nil // invalid, just ignore
+			}
+
+			osc.final = final
+			return p.handler.dispatchOperatingSystemCommand(osc)
+		}
+	case p.state == stateDCSPassthrough:
+		dcs := &deviceControlString{param: p.param.String(), intermediate: p.intermediate.String(), final: p.final, data: p.data.String()}
+		dispatch = func(final string) error {
+			dcs.terminator = final
+			return p.handler.dispatchDeviceControlString(dcs)
+		}
+	case p.state == stateSOSPMAPCString:
+		cs := &controlString{introducer: p.introducer, data: p.data.String()}
+		dispatch = func(final string) error {
+			cs.terminator = final
+			return p.handler.dispatchControlString(cs)
+		}
+	}
+
+	switch {
+	case r == '\u009C', r == '\u0007' && p.state == stateOSCString:
+		return dispatch(string(r))
+	case r == '\u001B':
+		start := p.start
+		p.pending = func(final string) error {
+			// the string starts before ESC
+			p.start = start
+			return dispatch(final)
+		}
+	}
+
+	return nil
+}
+
+// act performs the action with the character.
+func (p *parser) act(action parserAction, r rune) error {
+	switch action {
+	case actionPrint:
+		return p.handler.print(r)
+	case actionExecute:
+		return p.handler.execute(r)
+	case actionCollect:
+		if ok, err := p.fits(p.intermediate.Len() + utf8.RuneLen(r)); !ok {
+			return err
+		}
+
+		p.intermediate.WriteRune(r)
+	case actionIntroduce:
+		if r >= 0x80 {
+			// 8-bit introducer is equivalent to ESC Fe
+			r -= 0x40
+		}
+
+		p.introducer = string(r)
+	case actionHook:
+		p.final = string(r)
+	case actionParam:
+		if ok, err := p.fits(p.param.Len() + utf8.RuneLen(r)); !ok {
+			return err
+		}
+
+		p.param.WriteRune(r)
+	case actionPut:
+		if p.state == stateOSCString && r == ';' && !p.commandEnd {
+			p.commandEnd = true
+			if p.oscLimit != nil {
+				p.limit = p.oscLimit(p.data.String())
+			}
+		}
+
+		// discarded silently, since the payload is rejected by the handler anyway
+		if p.limit > 0 && p.data.Len()+utf8.RuneLen(r) > p.limit {
+			p.overflow = true
+			return nil
+		}
+
+		if ok, err := p.fits(p.data.Len() + utf8.RuneLen(r)); !ok {
+			return err
+		}
+
+		p.data.WriteRune(r)
+	case actionEscapeDispatch:
+		if p.pending != nil {
+			dispatch := p.pending
+			p.pending = nil
+
+			if p.intermediate.Len() == 0 && r == '\\' {
+				return dispatch("\u001B\\")
+			}
+		}
+
+		if p.overflow {
+			return nil
+		}
+
+		return p.handler.dispatchEscapeSequence(&escapeSequence{intermediate: p.intermediate.String(), final: string(r)})
+	case actionCSIDispatch:
+		if p.overflow {
+			return nil
+		}
+
+		return p.handler.dispatchControlSequence(&controlSequence{param: p.param.String(), intermediate: p.intermediate.String(), final: string(r)})
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"unicode/utf8"
+)
+
+// parsed represents a character or a sequence dispatched by the parser.
+type parsed struct {
+	kind string
+	str  string
+}
+
+// recorder records characters and sequences dispatched by the parser.
+// Consecutive printed characters are merged into one.
+type recorder struct {
+	parsed []parsed
+}
+
+func (rec *recorder) add(kind string, str string) error {
+	rec.parsed = append(rec.parsed, parsed{kind: kind, str: str})
+	return nil
+}
+
+func (rec *recorder) print(r rune) error {
+	if n := len(rec.parsed); n > 0 && rec.parsed[n-1].kind == "print" {
+		rec.parsed[n-1].str += string(r)
+		return nil
+	}
+
+	return rec.add("print", string(r))
+}
+
+func (rec *recorder) execute(r rune) error {
+	return rec.add("execute", string(r))
+}
+
+func (rec *recorder) dispatchEscapeSequence(es *escapeSequence) error {
+	return rec.add("esc", es.String())
+}
+
+func (rec *recorder) dispatchControlSequence(cs *controlSequence) error {
+	return rec.add("csi", cs.String())
+}
+
+func (rec *recorder) dispatchOperatingSystemCommand(osc *operatingSystemCommand) error {
+	return rec.add("osc", osc.String())
+}
+
+func (rec *recorder) dispatchDeviceControlString(dcs *deviceControlString) error {
+	return rec.add("dcs", dcs.String())
+}
+
+func (rec *recorder) dispatchControlString(cs *controlString) error {
+	return rec.add("string", cs.String())
+}
+
+func Test_parser(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected []parsed
+	}{
+		// escape sequences
+		{str: "\u001BNabc", expected: []parsed{{"esc", "\u001BN"}, {"print", "abc"}}},
+		{str: "\u001Bnabc", expected: []parsed{{"esc", "\u001Bn"}, {"print", "abc"}}},
+		{str: "\u001B(0abc", expected: []parsed{{"esc", "\u001B(0"}, {"print", "abc"}}},
+		{str: "\u001B)B", expected: []parsed{{"esc", "\u001B)B"}}},
+		{str: "\u001B#8abc", expected: []parsed{{"esc", "\u001B#8"}, {"print", "abc"}}},
+		{str: "\u001B%Gabc", expected: []parsed{{"esc", "\u001B%G"}, {"print", "abc"}}},
+		{str: "\u001B Fabc", expected: []parsed{{"esc", "\u001B F"}, {"print", "abc"}}},
+		{str: "\u001B7abc", expected: []parsed{{"esc", "\u001B7"}, {"print", "abc"}}},
+		{str: "\u001B=abc", expected: []parsed{{"esc", "\u001B="}, {"print", "abc"}}},
+		{str: "\u001B", expected: nil},
+		{str: "\u001B(", expected: nil},
+		{str: "\u001B\u0008abc", expected: []parsed{{"execute", "\u0008"}, {"esc", "\u001Ba"}, {"print", "bc"}}},
+		{str: "\u001B(\u007FBabc", expected: []parsed{{"esc", "\u001B(B"}, {"print", "abc"}}},
+		{str: "\u001Bあabc", expected: []parsed{{"print", "あabc"}}},
+		{str: "\u001B\u001BNabc", expected: []parsed{{"esc", "\u001BN"}, {"print", "abc"}}},
+
+		// control sequences
+		{str: "\u001B[Aabc", expected: []parsed{{"csi", "\u001B[A"}, {"print", "abc"}}},
+		{str: "\u001B[1;31mdef", expected: []parsed{{"csi", "\u001B[1;31m"}, {"print", "def"}}},
+		{str: "\u001B[0\"qghi", expected: []parsed{{"csi", "\u001B[0\"q"}, {"print", "ghi"}}},
+		{str: "\u001B[?25h", expected: []parsed{{"csi", "\u001B[?25h"}}},
+		{str: "\u001B[38:2:255:0:0m", expected: []parsed{{"csi", "\u001B[38:2:255:0:0m"}}},
+		{str: "\u001B[", expected: nil},
+		{str: "\u001B[123", expected: nil},
+		{str: "\u001B[0\"", expected: nil},
+		{str: "\u001B[1;31あ", expected: []parsed{{"print", "あ"}}},
+		{str: "\u001B[1\u000D;2Habc", expected: []parsed{{"execute", "\u000D"}, {"csi", "\u001B[1;2H"}, {"print", "abc"}}},
+		{str: "\u001B[1\u0018;2Habc", expected: []parsed{{"execute", "\u0018"}, {"print", ";2Habc"}}},
+		{str: "\u001B[1\u001A;2Habc", expected: []parsed{{"execute", "\u001A"}, {"print", ";2Habc"}}},
+		{str: "\u001B[1\u001B[2Habc", expected: []parsed{{"csi", "\u001B[2H"}, {"print", "abc"}}},
+		{str: "\u001B[1?2Habc", expected: []parsed{{"print", "abc"}}},
+		{str: "\u001B[1\"2Habc", expected: []parsed{{"print", "abc"}}},
+		{str: "\u009B2Jabc", expected: []parsed{{"csi", "\u001B[2J"}, {"print", "abc"}}},
+
+		// operating system commands
+		{str: "\u001B]0;Hello World\u0007abc", expected: []parsed{{"osc", "\u001B]0;Hello World\u0007"}, {"print", "abc"}}},
+		{str: "\u001B]0;Hello World\u001B\\def", expected: []parsed{{"osc", "\u001B]0;Hello World\u001B\\"}, {"print", "def"}}},
+		{str: "\u001B]0;Hello World\u009Cdef", expected: []parsed{{"osc", "\u001B]0;Hello World\u009C"}, {"print", "def"}}},
+		{str: "\u009D0;Hello World\u009Cdef", expected: []parsed{{"osc", "\u001B]0;Hello World\u009C"}, {"print", "def"}}},
+		{str: "\u001B]4;rgb:127/127/127\u001B\\", expected: []parsed{{"osc", "\u001B]4;rgb:127/127/127\u001B\\"}}},
+		{str: "\u001B]0;Hello\nWorld\u0007", expected: []parsed{{"osc", "\u001B]0;HelloWorld\u0007"}}},
+		{str: "\u001B]0;Hello World", expected: nil},
+		{str: "\u001B]0;Hello World\u001B", expected: nil},
+		{str: "\u001B]0;Hello\u001B World\u0007", expected: []parsed{{"esc", "\u001B W"}, {"print", "orld"}, {"execute", "\u0007"}}},
+		{str: "\u001B]0;Hello\u0018World\u0007", expected: []parsed{{"execute", "\u0018"}, {"print", "World"}, {"execute", "\u0007"}}},
+		{str: "\u001B]0;Hello\u0085World\u0007", expected: []parsed{{"execute", "\u0085"}, {"print", "World"}, {"execute", "\u0007"}}},
+		{str: "\u001B]104\u0007pqr", expected: []parsed{{"osc", "\u001B]104;\u0007"}, {"print", "pqr"}}},
+		{str: "\u001B]LHello World\u001B\\pqr", expected: []parsed{{"osc", "\u001B]LHello World\u001B\\"}, {"print", "pqr"}}},
+		{str: "\u001B]0Hello World\u0007pqr", expected: []parsed{{"print", "pqr"}}},
+		{str: "\u001B];Hello World\u0007pqr", expected: []parsed{{"print", "pqr"}}},
+
+		// device control strings and other strings
+		{str: "\u001BP1$qm\u001B\\abc", expected: []parsed{{"dcs", "\u001BP1$qm\u001B\\"}, {"print", "abc"}}},
+		{str: "\u001BPq#0;2;0;0;0\u001B\\abc", expected: []parsed{{"dcs", "\u001BPq#0;2;0;0;0\u001B\\"}, {"print", "abc"}}},
+		{str: "\u0090+q544e\u009Cabc", expected: []parsed{{"dcs", "\u001BP+q544e\u009C"}, {"print", "abc"}}},
+		{str: "\u001BP1$qm\u0018abc", expected: []parsed{{"execute", "\u0018"}, {"print", "abc"}}},
+		{str: "\u001BP1?2qm\u001B\\abc", expected: []parsed{{"esc", "\u001B\\"}, {"print", "abc"}}},
+		{str: "\u001B_Gf=100;AAAA\u001B\\abc", expected: []parsed{{"string", "\u001B_Gf=100;AAAA\u001B\\"}, {"print", "abc"}}},
+		{str: "\u001B^private\u009Cabc", expected: []parsed{{"string", "\u001B^private\u009C"}, {"print", "abc"}}},
+		{str: "\u0098start of string\u009Cabc", expected: []parsed{{"string", "\u001BXstart of string\u009C"}, {"print", "abc"}}},
+		{str: "\u001B_APC\u0007abc\u001B\\def", expected: []parsed{{"string", "\u001B_APCabc\u001B\\"}, {"print", "def"}}},
+
+		// C1 controls
+		{str: "abc\u0084def", expected: []parsed{{"print", "abc"}, {"execute", "\u0084"}, {"print", "def"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("str=%q", tt.str), func(t *testing.T) {
+			rec := &recorder{}
+			p := newParser(rec)
+
+			for _, r := range tt.str {
+				if err := p.advance(r, utf8.RuneLen(r)); err != nil {
+					t.Fatalf("advance() returned error: %v", err)
+				}
+			}
+
+			opt := cmp.AllowUnexported(parsed{})
+			if diff := cmp.Diff(tt.expected, rec.parsed, opt); diff != "" {
+				t.Errorf("parsed differs from expected\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,14 +3,51 @@ package escapefilter
 import (
 	"fmt"
 	"github.com/mattn/go-runewidth"
+	"math"
 	"strings"
 )
 
+// Default screen size, used by operations which needs the size of the screen.
+// Screen itself has no size limit.
+const (
+	defaultScreenRows = 24
+	defaultScreenCols = 80
+)
+
 // Screen stores character content and a cursor position.
 type Screen struct {
-	lines []string
+	lines []cells
 	row   int
 	col   int
+
+	// charsets stores character sets designated to G0-G3.
+	charsets [4]Charset
+	// gl is the index of the character set invoked into GL.
+	gl int
+	// singleShift is the index of the character set invoked by SS2/SS3 (0 if none).
+	singleShift int
+
+	// renditions stores line renditions other than single width, by row.
+	renditions map[int]LineRendition
+
+	// link is the hyperlink attached to characters put after this (nil if none).
+	link *Hyperlink
+	// links stores hyperlinks attached to each cell, by row and column.
+	links [][]*Hyperlink
+
+	// top is the number of rows discarded from the top, which can no longer be changed.
+	// lines and links start at the row top+1.
+	top int
+
+	// limits stores the limits of the screen size.
+	limits Limits
+	// size is the number of cells in lines (see cellsSize), which is counted only if limits.MaxCells is set.
+	size int
+	// err is the error reported by exceeding a limit in the strict policy (nil if none).
+	err error
+	// discarding is called with the last row before rows are discarded by MaxRows,
+	// so that they are written out in the streaming mode (nil if not streaming).
+	discarding func(row int)
 }
 
 // NewScreen returns a new empty Screen.
@@ -47,43 +84,185 @@ func findColumnPosition(line string, col int) (index int, offset int) {
 	return
 }
 
-// putRune puts a rune to the line and returns the new line and the column position.
-func putRune(line string, col int, r rune) (newLine string, newCol int) {
-	if col <= 0 {
-		panic(fmt.Sprintf("col must be >= 1, got %d", col))
+// PutRune puts a rune to the screen.
+// The rune is translated by the character set currently invoked.
+func (s *Screen) PutRune(r rune) {
+	g := s.gl
+	if s.singleShift != 0 {
+		g = s.singleShift
+		s.singleShift = 0
 	}
+	s.put(s.charsets[g].translate(r))
+}
 
+// putText puts the text to the screen as it is, regardless of the character sets.
+func (s *Screen) putText(text string) {
+	for _, r := range text {
+		s.put(r)
+	}
+}
+
+// put puts a rune to the screen without translation.
+// The rune is discarded if it doesn't fit in the limits.
+func (s *Screen) put(r rune) {
 	w := runewidth.RuneWidth(r)
-	if w <= 0 {
-		return line, col
+	i := s.row - s.top - 1
+
+	// a character beyond the last column of a double-width line overwrites the last column
+	if last := s.lastCol(s.row); last > 0 && w > 0 && s.col-1+w > last {
+		s.col = last - w + 1
 	}
 
-	l := runewidth.StringWidth(line)
+	if max := s.limits.MaxCols; max > 0 && w > 0 && s.col-1+w > max {
+		s.exceed(LimitCols, max)
+		return
+	}
 
-	var sb strings.Builder
+	if max := s.limits.MaxCells; max > 0 && w > 0 {
+		after := s.col - 1 + w
+		if n := len(s.line(s.row)); n > after {
+			after = n
+		}
 
-	if col > 1 {
-		before := runewidth.Truncate(line, col-1, "")
-		sb.WriteString(runewidth.FillRight(before, col-1))
+		// blank rows appended up to the line are also counted
+		grow := after - len(s.line(s.row))
+		if blank := i + 1 - len(s.lines); blank > 0 {
+			grow += blank * cellsSize(nil)
+		}
+
+		if s.size+grow > max {
+			s.exceed(LimitCells, max)
+			return
+		}
+	}
+
+	if blank := i + 1 - len(s.lines); blank > 0 {
+		s.lines = append(s.lines, make([]cells, blank)...)
+		if s.limits.MaxCells > 0 {
+			s.size += blank * cellsSize(nil)
+		}
 	}
 
-	sb.WriteRune(r)
+	s.putHyperlink(w)
 
-	if col+w <= l {
-		after := runewidth.TruncateLeft(line, col+w-1, "")
-		sb.WriteString(runewidth.FillLeft(after, l-(col+w)+1))
+	line, col := s.lines[i].put(s.col, r)
+	s.setLine(i, line)
+	s.col = col
+}
+
+// setLine replaces the line at the index, counting the cells.
+func (s *Screen) setLine(i int, line cells) {
+	if s.limits.MaxCells > 0 {
+		s.size += cellsSize(line) - cellsSize(s.lines[i])
 	}
 
-	return sb.String(), col + w
+	s.lines[i] = line
 }
 
-// PutRune puts a rune to the screen.
-func (s *Screen) PutRune(r rune) {
-	for len(s.lines) < s.row {
-		s.lines = append(s.lines, "")
+// setLines replaces all the lines, counting the cells.
+func (s *Screen) setLines(lines []cells) {
+	s.lines = lines
+
+	if s.limits.MaxCells > 0 {
+		s.size = 0
+		for _, line := range lines {
+			s.size += cellsSize(line)
+		}
+	}
+}
+
+// setLimits sets the limits of the screen size.
+// The existing content is kept even if it exceeds the limits.
+func (s *Screen) setLimits(l Limits) {
+	s.limits = l
+	s.setLines(s.lines)
+}
+
+// line returns the line at the row, or nil if the row is beyond the content or discarded.
+func (s *Screen) line(row int) cells {
+	i := row - s.top - 1
+	if i < 0 || len(s.lines) <= i {
+		return nil
+	}
+
+	return s.lines[i]
+}
+
+// DesignateCharset designates the character set to G0-G3.
+func (s *Screen) DesignateCharset(g int, cs Charset) {
+	if g < 0 || 3 < g {
+		panic(fmt.Sprintf("g must be 0-3, got %d", g))
+	}
+
+	s.charsets[g] = cs
+}
+
+// LockingShift invokes G0-G3 into GL.
+func (s *Screen) LockingShift(g int) {
+	if g < 0 || 3 < g {
+		panic(fmt.Sprintf("g must be 0-3, got %d", g))
+	}
+
+	s.gl = g
+}
+
+// SingleShift invokes G2 or G3 into GL for the next character only.
+func (s *Screen) SingleShift(g int) {
+	if g != 2 && g != 3 {
+		panic(fmt.Sprintf("g must be 2 or 3, got %d", g))
+	}
+
+	s.singleShift = g
+}
+
+// SetLineRendition sets the rendition of the current line.
+// Characters beyond the half of the screen are lost on a line other than single width.
+func (s *Screen) SetLineRendition(l LineRendition) {
+	if l == SingleWidth {
+		delete(s.renditions, s.row)
+		return
+	}
+
+	if s.renditions == nil {
+		s.renditions = map[int]LineRendition{}
+	}
+
+	s.renditions[s.row] = l
+
+	if i := s.row - s.top - 1; i < len(s.lines) {
+		s.setLine(i, s.lines[i].truncate(doubleWidthCols))
+	}
+	s.eraseHyperlinks(s.row, doubleWidthCols+1, math.MaxInt)
+	s.MoveCursor(s.row, s.col)
+}
+
+// LineRendition returns the rendition of the line at the row.
+func (s *Screen) LineRendition(row int) LineRendition {
+	return s.renditions[row]
+}
+
+// resetLineRenditions resets renditions of the lines in rows [from, to] to single width.
+func (s *Screen) resetLineRenditions(from int, to int) {
+	for row := range s.renditions {
+		if from <= row && row <= to {
+			delete(s.renditions, row)
+		}
 	}
 
-	s.lines[s.row-1], s.col = putRune(s.lines[s.row-1], s.col, r)
+	if len(s.renditions) == 0 {
+		s.renditions = nil
+	}
+}
+
+// Position represents a position in the screen (1-based).
+type Position struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// Position returns the current cursor position.
+func (s *Screen) Position() Position {
+	return Position{Row: s.row, Col: s.col}
 }
 
 // Row returns the current row position (1-based).
@@ -125,114 +304,221 @@ func (s *Screen) NextTabStop(n int) int {
 }
 
 // MoveCursor moves the cursor position to (row, col).
+// The cursor stays below the discarded rows, within the half of the screen on a line other than single width,
+// within MaxCols, and within MaxCells counting blank rows.
+// Moving beyond MaxRows discards rows from the top, like scrolling.
 func (s *Screen) MoveCursor(row int, col int) {
-	if row <= 0 {
-		row = 1
+	if row <= s.top {
+		row = s.top + 1
 	}
 
 	if col <= 0 {
 		col = 1
 	}
 
+	if max := s.limits.MaxRows; max > 0 && row-s.top > max {
+		s.exceed(LimitRows, max)
+		s.scroll(row - max)
+	}
+
+	// blank rows below the content are output up to the cursor
+	if max := s.limits.MaxCells; max > 0 && row-s.top-len(s.lines) > 0 && s.size+(row-s.top-len(s.lines))*cellsSize(nil) > max {
+		s.exceed(LimitCells, max)
+		row = s.top + len(s.lines) + (max-s.size)/cellsSize(nil)
+		if row <= s.top+len(s.lines) {
+			row = s.top + len(s.lines)
+			if row <= s.top {
+				row = s.top + 1
+			}
+		}
+	}
+
+	if last := s.lastCol(row); last > 0 && col > last {
+		col = last
+	}
+
+	if max := s.limits.MaxCols; max > 0 && col > max {
+		// just after the last column is where the cursor is after putting a character there
+		if col > max+1 {
+			s.exceed(LimitCols, max)
+		}
+		col = max
+	}
+
 	s.row = row
 	s.col = col
 }
 
 // removeExtraBlankLines removes blank lines at the bottom.
-func removeExtraBlankLines(lines []string) []string {
+func removeExtraBlankLines(lines []cells) []cells {
 	var r int
-	for r = len(lines); r >= 1 && lines[r-1] == ""; r-- {
+	for r = len(lines); r >= 1 && len(lines[r-1]) == 0; r-- {
+	}
+	return lines[:r]
+}
+
+// trimLines removes blank lines at the bottom, counting the cells.
+func (s *Screen) trimLines() {
+	lines := removeExtraBlankLines(s.lines)
+	if s.limits.MaxCells > 0 {
+		s.size -= (len(s.lines) - len(lines)) * cellsSize(nil)
 	}
-	return append([]string{}, lines[:r]...)
+
+	s.lines = lines
 }
 
 // EraseLineAfter erases characters from the current position to the end of the line.
 func (s *Screen) EraseLineAfter() {
-	if s.row <= len(s.lines) {
-		if s.col > 1 {
-			s.lines[s.row-1] = runewidth.Truncate(s.lines[s.row-1], s.col-1, "")
-		} else {
-			s.lines[s.row-1] = ""
-		}
+	if i := s.row - s.top - 1; i < len(s.lines) {
+		s.setLine(i, s.lines[i].truncate(s.col-1))
 	}
 
-	s.lines = removeExtraBlankLines(s.lines)
+	s.eraseHyperlinks(s.row, s.col, math.MaxInt)
+	s.trimLines()
+	s.trimHyperlinks()
 }
 
 // EraseLineBefore erases characters from the current position to the beginning of the line.
 func (s *Screen) EraseLineBefore() {
-	if s.row <= len(s.lines) {
-		l := runewidth.StringWidth(s.lines[s.row-1])
-		if s.col < l {
-			rest := runewidth.TruncateLeft(s.lines[s.row-1], s.col, "")
-			s.lines[s.row-1] = runewidth.FillLeft(rest, l)
-		} else {
-			s.lines[s.row-1] = ""
-		}
+	if i := s.row - s.top - 1; i < len(s.lines) {
+		s.setLine(i, s.lines[i].eraseBefore(s.col))
 	}
 
-	s.lines = removeExtraBlankLines(s.lines)
+	s.eraseHyperlinks(s.row, 1, s.col)
+	s.trimLines()
+	s.trimHyperlinks()
 }
 
 // EraseLineBefore erases characters in the current row.
 func (s *Screen) EraseLine() {
-	if s.row <= len(s.lines) {
-		s.lines[s.row-1] = ""
+	if i := s.row - s.top - 1; i < len(s.lines) {
+		s.setLine(i, nil)
 	}
 
-	s.lines = removeExtraBlankLines(s.lines)
+	s.eraseHyperlinks(s.row, 1, math.MaxInt)
+	s.trimLines()
+	s.trimHyperlinks()
 }
 
 // EraseScreenAfter erases characters from the current position to the end of the screen.
 func (s *Screen) EraseScreenAfter() {
-	if s.row > len(s.lines) {
+	if s.row-s.top > len(s.lines) {
 		return
 	}
 
-	s.lines = append([]string{}, s.lines[:s.row]...)
+	s.setLines(s.lines[:s.row-s.top])
+	s.resetLineRenditions(s.row+1, math.MaxInt)
 	s.EraseLineAfter()
 }
 
 // EraseScreenBefore erases characters from the current position to the beginning of the screen.
 func (s *Screen) EraseScreenBefore() {
-	if s.row > len(s.lines) {
-		s.EraseScreen()
+	if s.row-s.top > len(s.lines) {
+		s.setLines([]cells{})
+		s.resetLineRenditions(1, s.row)
+		s.links = nil
 		return
 	}
 
-	for r := 1; r < s.row; r++ {
-		s.lines[r-1] = ""
+	for r := s.top + 1; r < s.row; r++ {
+		s.setLine(r-s.top-1, nil)
+		s.eraseHyperlinks(r, 1, math.MaxInt)
 	}
+	s.resetLineRenditions(1, s.row-1)
 
 	s.EraseLineBefore()
 }
 
 // EraseScreen erases characters in the entire screen.
 func (s *Screen) EraseScreen() {
-	s.lines = []string{}
+	s.setLines([]cells{})
+	s.renditions = nil
+	s.links = nil
 }
 
-// String returns string content of the screen.
-// If the cursor is farther than the end of the content, additional lines and spaces will be added.
-func (s *Screen) String() string {
-	var sb strings.Builder
-
+// FillScreen fills the screen with the rune and moves the cursor to the home position.
+// At least the default screen size is filled, and also all the existing lines are, within the limits.
+func (s *Screen) FillScreen(r rune) {
 	n := len(s.lines)
+	if n < defaultScreenRows {
+		n = defaultScreenRows
+	}
+
+	cols := defaultScreenCols
+
+	if max := s.limits.MaxRows; max > 0 && n > max {
+		s.exceed(LimitRows, max)
+		n = max
+	}
+
+	if max := s.limits.MaxCols; max > 0 && cols > max {
+		s.exceed(LimitCols, max)
+		cols = max
+	}
+
+	if max := s.limits.MaxCells; max > 0 && n*cols > max {
+		s.exceed(LimitCells, max)
+		n = max / cols
+	}
 
-	for r := 1; r <= n-1; r++ {
-		sb.WriteString(s.lines[r-1])
-		sb.WriteRune('\n')
+	lines := make([]cells, n)
+	for i := range lines {
+		lines[i] = newCells(strings.Repeat(string(r), cols))
 	}
+	s.setLines(lines)
+	s.renditions = nil
+	s.links = nil
 
-	if s.row == n {
-		sb.WriteString(runewidth.FillRight(s.lines[n-1], s.col-1))
+	s.MoveCursor(1, 1)
+}
+
+// rows returns the last row to output, which is the cursor row if beyond the content.
+// Rows up to top have been discarded.
+func (s *Screen) rows() int {
+	if s.row > s.top+len(s.lines) {
+		return s.row
+	}
+
+	return s.top + len(s.lines)
+}
+
+// discard removes the rows up to the row from the screen, so that they can no longer be changed.
+// The cursor is moved below them if needed.
+func (s *Screen) discard(row int) {
+	n := row - s.top
+	if n <= 0 {
+		return
+	}
+
+	if n < len(s.lines) {
+		s.setLines(s.lines[n:])
 	} else {
-		sb.WriteString(s.lines[n-1])
+		s.setLines(nil)
 	}
 
-	for r := n + 1; r <= s.row; r++ {
-		sb.WriteRune('\n')
+	if n < len(s.links) {
+		s.links = s.links[n:]
+	} else {
+		s.links = nil
+	}
+
+	s.resetLineRenditions(1, row)
+	s.top = row
+
+	s.MoveCursor(s.row, s.col)
+}
+
+// scroll discards the rows up to the row, after writing them out in the streaming mode.
+func (s *Screen) scroll(row int) {
+	if s.discarding != nil {
+		s.discarding(row)
 	}
 
-	return sb.String()
+	s.discard(row)
+}
+
+// String returns string content of the screen.
+// If the cursor is farther than the end of the content, additional lines and spaces will be added.
+func (s *Screen) String() string {
+	return s.Text(TextOptions{})
 }
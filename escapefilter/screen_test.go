@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/andreyvit/diff"
 	"github.com/google/go-cmp/cmp"
+	"strings"
 	"testing"
 )
 
@@ -23,108 +24,6 @@ func Test_NewScreen(t *testing.T) {
 	}
 }
 
-func Test_putRune(t *testing.T) {
-	tests := []struct {
-		line    string
-		col     int
-		r       rune
-		newLine string
-		newCol  int
-	}{
-		{
-			line:    "Hello World",
-			col:     12,
-			r:       'X',
-			newLine: "Hello WorldX",
-			newCol:  13,
-		},
-		{
-			line:    "Hello World",
-			col:     5,
-			r:       'X',
-			newLine: "HellX World",
-			newCol:  6,
-		},
-		{
-			line:    "Hello World",
-			col:     15,
-			r:       'X',
-			newLine: "Hello World   X",
-			newCol:  16,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     18,
-			r:       'あ',
-			newLine: "こんにちはABC世界あ",
-			newCol:  20,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     5,
-			r:       'あ',
-			newLine: "こんあちはABC世界",
-			newCol:  7,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     6,
-			r:       'あ',
-			newLine: "こん あ はABC世界",
-			newCol:  8,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     11,
-			r:       'あ',
-			newLine: "こんにちはあC世界",
-			newCol:  13,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     13,
-			r:       'あ',
-			newLine: "こんにちはABあ 界",
-			newCol:  15,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     14,
-			r:       'あ',
-			newLine: "こんにちはABCあ界",
-			newCol:  16,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     20,
-			r:       'あ',
-			newLine: "こんにちはABC世界  あ",
-			newCol:  22,
-		},
-		{
-			line:    "こんにちはABC世界",
-			col:     18,
-			r:       '\u0000',
-			newLine: "こんにちはABC世界",
-			newCol:  18,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(fmt.Sprintf("line=%s,col=%d,r=%U", tt.line, tt.col, tt.r), func(t *testing.T) {
-			newLine, newCol := putRune(tt.line, tt.col, tt.r)
-
-			if newLine != tt.newLine {
-				t.Errorf("newLine differs from expected\n%v", diff.LineDiff(tt.newLine, newLine))
-			}
-
-			if newCol != tt.newCol {
-				t.Errorf("newCol should be %d, got %d", tt.newCol, newCol)
-			}
-		})
-	}
-}
-
 func Test_Screen_PutRune(t *testing.T) {
 	lines := []string{"Hello World", "こんにちはABC世界"}
 
@@ -139,7 +38,7 @@ func Test_Screen_PutRune(t *testing.T) {
 			col: 5,
 			r:   'X',
 			expected: &Screen{
-				lines: []string{"HellX World", "こんにちはABC世界"},
+				lines: newLines("HellX World", "こんにちはABC世界"),
 				row:   1,
 				col:   6,
 			},
@@ -149,7 +48,7 @@ func Test_Screen_PutRune(t *testing.T) {
 			col: 15,
 			r:   'X',
 			expected: &Screen{
-				lines: []string{"Hello World   X", "こんにちはABC世界"},
+				lines: newLines("Hello World   X", "こんにちはABC世界"),
 				row:   1,
 				col:   16,
 			},
@@ -159,7 +58,7 @@ func Test_Screen_PutRune(t *testing.T) {
 			col: 6,
 			r:   'あ',
 			expected: &Screen{
-				lines: []string{"Hello World", "こん あ はABC世界"},
+				lines: newLines("Hello World", "こん あ はABC世界"),
 				row:   2,
 				col:   8,
 			},
@@ -169,7 +68,7 @@ func Test_Screen_PutRune(t *testing.T) {
 			col: 18,
 			r:   'あ',
 			expected: &Screen{
-				lines: []string{"Hello World", "こんにちはABC世界あ"},
+				lines: newLines("Hello World", "こんにちはABC世界あ"),
 				row:   2,
 				col:   20,
 			},
@@ -179,7 +78,7 @@ func Test_Screen_PutRune(t *testing.T) {
 			col: 1,
 			r:   'あ',
 			expected: &Screen{
-				lines: []string{"Hello World", "こんにちはABC世界", "あ"},
+				lines: newLines("Hello World", "こんにちはABC世界", "あ"),
 				row:   3,
 				col:   3,
 			},
@@ -189,7 +88,7 @@ func Test_Screen_PutRune(t *testing.T) {
 			col: 2,
 			r:   'あ',
 			expected: &Screen{
-				lines: []string{"Hello World", "こんにちはABC世界", "", " あ"},
+				lines: newLines("Hello World", "こんにちはABC世界", "", " あ"),
 				row:   4,
 				col:   4,
 			},
@@ -198,7 +97,7 @@ func Test_Screen_PutRune(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("row=%d,col=%d,r=%q", tt.row, tt.col, tt.r), func(t *testing.T) {
-			s := &Screen{lines: append([]string{}, lines...), row: tt.row, col: tt.col}
+			s := &Screen{lines: newLines(lines...), row: tt.row, col: tt.col}
 			s.PutRune(tt.r)
 
 			opt := cmp.AllowUnexported(*tt.expected)
@@ -209,6 +108,60 @@ func Test_Screen_PutRune(t *testing.T) {
 	}
 }
 
+func Test_Screen_PutRune_Charset(t *testing.T) {
+	tests := []struct {
+		charsets    [4]Charset
+		gl          int
+		singleShift int
+		str         string
+		expected    string
+	}{
+		{
+			str:      "lqqk",
+			expected: "lqqk",
+		},
+		{
+			charsets: [4]Charset{CharsetDECSpecialGraphics},
+			str:      "lqqk",
+			expected: "┌──┐",
+		},
+		{
+			charsets: [4]Charset{CharsetASCII, CharsetDECSpecialGraphics},
+			str:      "lqqk",
+			expected: "lqqk",
+		},
+		{
+			charsets: [4]Charset{CharsetASCII, CharsetDECSpecialGraphics},
+			gl:       1,
+			str:      "lqqk",
+			expected: "┌──┐",
+		},
+		{
+			charsets:    [4]Charset{CharsetASCII, CharsetASCII, CharsetUK, CharsetDECSpecialGraphics},
+			singleShift: 3,
+			str:         "x#",
+			expected:    "│#",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("charsets=%v,gl=%d,singleShift=%d", tt.charsets, tt.gl, tt.singleShift), func(t *testing.T) {
+			s := &Screen{row: 1, col: 1, charsets: tt.charsets, gl: tt.gl, singleShift: tt.singleShift}
+			for _, r := range tt.str {
+				s.PutRune(r)
+			}
+
+			if actual := s.String(); actual != tt.expected {
+				t.Errorf("String() should return %q, got %q", tt.expected, actual)
+			}
+
+			if s.singleShift != 0 {
+				t.Errorf("singleShift should be reset, got %d", s.singleShift)
+			}
+		})
+	}
+}
+
 func Test_Screen_MoveCursor(t *testing.T) {
 	tests := []struct {
 		row int
@@ -238,6 +191,51 @@ func Test_Screen_MoveCursor(t *testing.T) {
 	}
 }
 
+func Test_Screen_SetLineRendition(t *testing.T) {
+	s := &Screen{lines: newLines("Hello", "World"), row: 2, col: 1}
+
+	s.SetLineRendition(DoubleWidth)
+	if l := s.LineRendition(2); l != DoubleWidth {
+		t.Errorf("LineRendition(2) should return %v, got %v", DoubleWidth, l)
+	}
+
+	if l := s.LineRendition(1); l != SingleWidth {
+		t.Errorf("LineRendition(1) should return %v, got %v", SingleWidth, l)
+	}
+
+	s.SetLineRendition(SingleWidth)
+	if l := s.LineRendition(2); l != SingleWidth {
+		t.Errorf("LineRendition(2) should return %v, got %v", SingleWidth, l)
+	}
+}
+
+func Test_Screen_EraseScreen_LineRendition(t *testing.T) {
+	tests := []struct {
+		erase    func(s *Screen)
+		expected map[int]LineRendition
+	}{
+		{erase: (*Screen).EraseScreenAfter, expected: map[int]LineRendition{1: DoubleWidth, 2: DoubleWidth}},
+		{erase: (*Screen).EraseScreenBefore, expected: map[int]LineRendition{2: DoubleWidth, 3: DoubleWidth}},
+		{erase: (*Screen).EraseScreen, expected: nil},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			s := &Screen{
+				lines:      newLines("Hello", "World", "!"),
+				row:        2,
+				col:        3,
+				renditions: map[int]LineRendition{1: DoubleWidth, 2: DoubleWidth, 3: DoubleWidth},
+			}
+			tt.erase(s)
+
+			if diff := cmp.Diff(tt.expected, s.renditions); diff != "" {
+				t.Errorf("renditions differs from expected\n%s", diff)
+			}
+		})
+	}
+}
+
 func Test_Screen_PrevTabStop(t *testing.T) {
 	tests := []struct {
 		row int
@@ -305,7 +303,7 @@ func Test_Screen_EraseLineAfter(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   1,
-				lines: []string{"", "こんにちはABC世界"},
+				lines: newLines("", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -314,7 +312,7 @@ func Test_Screen_EraseLineAfter(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   8,
-				lines: []string{"Hello W", "こんにちはABC世界"},
+				lines: newLines("Hello W", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -323,7 +321,7 @@ func Test_Screen_EraseLineAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   1,
-				lines: []string{"Hello World"},
+				lines: newLines("Hello World"),
 			},
 		},
 		{
@@ -332,7 +330,7 @@ func Test_Screen_EraseLineAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   6,
-				lines: []string{"Hello World", "こん"},
+				lines: newLines("Hello World", "こん"),
 			},
 		},
 		{
@@ -341,7 +339,7 @@ func Test_Screen_EraseLineAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   17,
-				lines: []string{"Hello World", "こんにちはABC世"},
+				lines: newLines("Hello World", "こんにちはABC世"),
 			},
 		},
 		{
@@ -350,7 +348,7 @@ func Test_Screen_EraseLineAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   19,
-				lines: []string{"Hello World", "こんにちはABC世界"},
+				lines: newLines("Hello World", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -359,14 +357,14 @@ func Test_Screen_EraseLineAfter(t *testing.T) {
 			expected: &Screen{
 				row:   4,
 				col:   5,
-				lines: []string{"Hello World", "こんにちはABC世界"},
+				lines: newLines("Hello World", "こんにちはABC世界"),
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("row=%d,col=%d", tt.row, tt.col), func(t *testing.T) {
-			s := &Screen{lines: append([]string{}, lines...), row: tt.row, col: tt.col}
+			s := &Screen{lines: newLines(lines...), row: tt.row, col: tt.col}
 			s.EraseLineAfter()
 
 			opt := cmp.AllowUnexported(*tt.expected)
@@ -391,7 +389,7 @@ func Test_Screen_EraseLineBefore(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   1,
-				lines: []string{" ello World", "こんにちはABC世界"},
+				lines: newLines(" ello World", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -400,7 +398,7 @@ func Test_Screen_EraseLineBefore(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   8,
-				lines: []string{"        rld", "こんにちはABC世界"},
+				lines: newLines("        rld", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -409,7 +407,7 @@ func Test_Screen_EraseLineBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   1,
-				lines: []string{"Hello World", "  んにちはABC世界"},
+				lines: newLines("Hello World", "  んにちはABC世界"),
 			},
 		},
 		{
@@ -418,7 +416,7 @@ func Test_Screen_EraseLineBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   6,
-				lines: []string{"Hello World", "      ちはABC世界"},
+				lines: newLines("Hello World", "      ちはABC世界"),
 			},
 		},
 		{
@@ -427,7 +425,7 @@ func Test_Screen_EraseLineBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   17,
-				lines: []string{"Hello World"},
+				lines: newLines("Hello World"),
 			},
 		},
 		{
@@ -436,7 +434,7 @@ func Test_Screen_EraseLineBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   19,
-				lines: []string{"Hello World"},
+				lines: newLines("Hello World"),
 			},
 		},
 		{
@@ -445,14 +443,14 @@ func Test_Screen_EraseLineBefore(t *testing.T) {
 			expected: &Screen{
 				row:   4,
 				col:   5,
-				lines: []string{"Hello World", "こんにちはABC世界"},
+				lines: newLines("Hello World", "こんにちはABC世界"),
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("row=%d,col=%d", tt.row, tt.col), func(t *testing.T) {
-			s := &Screen{lines: append([]string{}, lines...), row: tt.row, col: tt.col}
+			s := &Screen{lines: newLines(lines...), row: tt.row, col: tt.col}
 			s.EraseLineBefore()
 
 			opt := cmp.AllowUnexported(*tt.expected)
@@ -477,7 +475,7 @@ func Test_Screen_EraseLine(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   8,
-				lines: []string{"", "こんにちはABC世界"},
+				lines: newLines("", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -486,14 +484,14 @@ func Test_Screen_EraseLine(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   8,
-				lines: []string{"Hello World"},
+				lines: newLines("Hello World"),
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("row=%d,col=%d", tt.row, tt.col), func(t *testing.T) {
-			s := &Screen{lines: append([]string{}, lines...), row: tt.row, col: tt.col}
+			s := &Screen{lines: newLines(lines...), row: tt.row, col: tt.col}
 			s.EraseLine()
 
 			opt := cmp.AllowUnexported(*tt.expected)
@@ -518,7 +516,7 @@ func Test_Screen_EraseScreenAfter(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   1,
-				lines: []string{},
+				lines: newLines(),
 			},
 		},
 		{
@@ -527,7 +525,7 @@ func Test_Screen_EraseScreenAfter(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   8,
-				lines: []string{"Hello W"},
+				lines: newLines("Hello W"),
 			},
 		},
 		{
@@ -536,7 +534,7 @@ func Test_Screen_EraseScreenAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   1,
-				lines: []string{"Hello World"},
+				lines: newLines("Hello World"),
 			},
 		},
 		{
@@ -545,7 +543,7 @@ func Test_Screen_EraseScreenAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   6,
-				lines: []string{"Hello World", "こん"},
+				lines: newLines("Hello World", "こん"),
 			},
 		},
 		{
@@ -554,7 +552,7 @@ func Test_Screen_EraseScreenAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   17,
-				lines: []string{"Hello World", "こんにちはABC世"},
+				lines: newLines("Hello World", "こんにちはABC世"),
 			},
 		},
 		{
@@ -563,7 +561,7 @@ func Test_Screen_EraseScreenAfter(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   19,
-				lines: []string{"Hello World", "こんにちはABC世界"},
+				lines: newLines("Hello World", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -572,14 +570,14 @@ func Test_Screen_EraseScreenAfter(t *testing.T) {
 			expected: &Screen{
 				row:   4,
 				col:   5,
-				lines: []string{"Hello World", "こんにちはABC世界"},
+				lines: newLines("Hello World", "こんにちはABC世界"),
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("row=%d,col=%d", tt.row, tt.col), func(t *testing.T) {
-			s := &Screen{lines: append([]string{}, lines...), row: tt.row, col: tt.col}
+			s := &Screen{lines: newLines(lines...), row: tt.row, col: tt.col}
 			s.EraseScreenAfter()
 
 			opt := cmp.AllowUnexported(*tt.expected)
@@ -604,7 +602,7 @@ func Test_Screen_EraseScreenBefore(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   1,
-				lines: []string{" ello World", "こんにちはABC世界"},
+				lines: newLines(" ello World", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -613,7 +611,7 @@ func Test_Screen_EraseScreenBefore(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   8,
-				lines: []string{"        rld", "こんにちはABC世界"},
+				lines: newLines("        rld", "こんにちはABC世界"),
 			},
 		},
 		{
@@ -622,7 +620,7 @@ func Test_Screen_EraseScreenBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   1,
-				lines: []string{"", "  んにちはABC世界"},
+				lines: newLines("", "  んにちはABC世界"),
 			},
 		},
 		{
@@ -631,7 +629,7 @@ func Test_Screen_EraseScreenBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   6,
-				lines: []string{"", "      ちはABC世界"},
+				lines: newLines("", "      ちはABC世界"),
 			},
 		},
 		{
@@ -640,7 +638,7 @@ func Test_Screen_EraseScreenBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   17,
-				lines: []string{},
+				lines: newLines(),
 			},
 		},
 		{
@@ -649,7 +647,7 @@ func Test_Screen_EraseScreenBefore(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   19,
-				lines: []string{},
+				lines: newLines(),
 			},
 		},
 		{
@@ -658,14 +656,14 @@ func Test_Screen_EraseScreenBefore(t *testing.T) {
 			expected: &Screen{
 				row:   4,
 				col:   5,
-				lines: []string{},
+				lines: newLines(),
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("row=%d,col=%d", tt.row, tt.col), func(t *testing.T) {
-			s := &Screen{lines: append([]string{}, lines...), row: tt.row, col: tt.col}
+			s := &Screen{lines: newLines(lines...), row: tt.row, col: tt.col}
 			s.EraseScreenBefore()
 
 			opt := cmp.AllowUnexported(*tt.expected)
@@ -690,7 +688,7 @@ func Test_Screen_EraseScreen(t *testing.T) {
 			expected: &Screen{
 				row:   1,
 				col:   8,
-				lines: []string{},
+				lines: newLines(),
 			},
 		},
 		{
@@ -699,14 +697,14 @@ func Test_Screen_EraseScreen(t *testing.T) {
 			expected: &Screen{
 				row:   2,
 				col:   8,
-				lines: []string{},
+				lines: newLines(),
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(fmt.Sprintf("row=%d,col=%d", tt.row, tt.col), func(t *testing.T) {
-			s := &Screen{lines: append([]string{}, lines...), row: tt.row, col: tt.col}
+			s := &Screen{lines: newLines(lines...), row: tt.row, col: tt.col}
 			s.EraseScreen()
 
 			opt := cmp.AllowUnexported(*tt.expected)
@@ -717,11 +715,133 @@ func Test_Screen_EraseScreen(t *testing.T) {
 	}
 }
 
+func Test_Screen_FillScreen(t *testing.T) {
+	tests := []struct {
+		lines []cells
+		rows  int
+	}{
+		{lines: newLines("Hello World", "こんにちはABC世界"), rows: 24},
+		{lines: make([]cells, 30), rows: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("lines=%d", len(tt.lines)), func(t *testing.T) {
+			s := &Screen{lines: tt.lines, row: 2, col: 8}
+			s.FillScreen('E')
+
+			if len(s.lines) != tt.rows {
+				t.Fatalf("lines should have length %d, got %d", tt.rows, len(s.lines))
+			}
+
+			for i, line := range s.lines {
+				if expected := strings.Repeat("E", 80); line.String() != expected {
+					t.Errorf("line %d should be %q, got %q", i+1, expected, line)
+				}
+			}
+
+			if row, col := s.Row(), s.Col(); row != 1 || col != 1 {
+				t.Errorf("cursor should be at (%d, %d), got (%d, %d)", 1, 1, row, col)
+			}
+		})
+	}
+}
+
 func Test_Screen_String(t *testing.T) {
-	s := &Screen{lines: []string{"Hello World", "こんにちはABC世界"}, row: 2, col: 18}
+	s := &Screen{lines: newLines("Hello World", "こんにちはABC世界"), row: 2, col: 18}
 	expected := "Hello World\nこんにちはABC世界"
 
 	if actual := s.String(); actual != expected {
 		t.Errorf("String() differs from expected\n%v", diff.LineDiff(expected, actual))
 	}
 }
+
+func Test_Screen_discard(t *testing.T) {
+	link := &Hyperlink{URI: "https://example.com/"}
+
+	tests := []struct {
+		row      int
+		expected *Screen
+	}{
+		{
+			row: 0,
+			expected: &Screen{
+				row:        3,
+				col:        2,
+				lines:      newLines("a", "b", "c"),
+				renditions: map[int]LineRendition{1: DoubleWidth, 3: DoubleWidth},
+				links:      [][]*Hyperlink{nil, {link}},
+			},
+		},
+		{
+			row: 1,
+			expected: &Screen{
+				row:        3,
+				col:        2,
+				lines:      newLines("b", "c"),
+				renditions: map[int]LineRendition{3: DoubleWidth},
+				links:      [][]*Hyperlink{{link}},
+				top:        1,
+			},
+		},
+		{
+			row: 3,
+			expected: &Screen{
+				row:        4,
+				col:        2,
+				lines:      nil,
+				renditions: nil,
+				links:      nil,
+				top:        3,
+			},
+		},
+		{
+			row: 5,
+			expected: &Screen{
+				row:        6,
+				col:        2,
+				lines:      nil,
+				renditions: nil,
+				links:      nil,
+				top:        5,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("row=%d", tt.row), func(t *testing.T) {
+			s := &Screen{
+				row:        3,
+				col:        2,
+				lines:      newLines("a", "b", "c"),
+				renditions: map[int]LineRendition{1: DoubleWidth, 3: DoubleWidth},
+				links:      [][]*Hyperlink{nil, {link}},
+			}
+			s.discard(tt.row)
+
+			opt := cmp.AllowUnexported(*tt.expected)
+			if diff := cmp.Diff(tt.expected, s, opt); diff != "" {
+				t.Errorf("%s", diff)
+			}
+		})
+	}
+}
+
+func Test_Screen_discard_Rows(t *testing.T) {
+	s := &Screen{row: 1, col: 1}
+	for _, r := range "abc" {
+		s.PutRune(r)
+		s.MoveCursor(s.Row()+1, 1)
+	}
+
+	s.discard(2)
+	s.MoveCursor(1, 3)
+	s.PutRune('x')
+
+	if actual := s.String(); actual != "c x" {
+		t.Errorf("String() should return %q, got %q", "c x", actual)
+	}
+
+	if pos := s.Position(); pos != (Position{Row: 3, Col: 4}) {
+		t.Errorf("Position() should return %v, got %v", Position{Row: 3, Col: 4}, pos)
+	}
+}
@@ -0,0 +1,319 @@
+package escapefilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MarkKind represents the kind of a shell integration mark.
+type MarkKind string
+
+// Shell integration marks set by OSC 133 (and OSC 633)
+const (
+	MarkPromptStart     MarkKind = "A"
+	MarkCommandStart    MarkKind = "B"
+	MarkCommandExecuted MarkKind = "C"
+	MarkCommandFinished MarkKind = "D"
+	MarkCommandLine     MarkKind = "E"
+)
+
+// MarkNavigation is a mark set by OSC 1337 ; SetMark (iTerm2) for navigation, which doesn't split commands.
+const MarkNavigation MarkKind = "SetMark"
+
+// Mark represents a shell integration mark.
+type Mark struct {
+	Position
+
+	Kind MarkKind `json:"kind"`
+	// ExitCode is the exit code of the command (MarkCommandFinished only, nil if not reported).
+	ExitCode *int `json:"exitCode,omitempty"`
+	// CommandLine is the command line reported explicitly (MarkCommandLine only).
+	CommandLine string `json:"commandLine,omitempty"`
+}
+
+// unescape633 unescapes the value of OSC 633, in which "\\" and "\xAB" are escaped.
+func unescape633(str string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) {
+			if str[i+1] == '\\' {
+				sb.WriteByte('\\')
+				i++
+				continue
+			}
+
+			if str[i+1] == 'x' && i+3 < len(str) {
+				if b, err := strconv.ParseUint(str[i+2:i+4], 16, 8); err == nil {
+					sb.WriteByte(byte(b))
+					i += 3
+					continue
+				}
+			}
+		}
+
+		sb.WriteByte(str[i])
+	}
+
+	return sb.String()
+}
+
+// parseMark parses the parameter of OSC 133 or OSC 633.
+// parseMark returns nil for unsupported marks.
+func parseMark(pos Position, param string, vscode bool) (*Mark, error) {
+	params := strings.Split(param, ";")
+
+	mark := &Mark{Position: pos, Kind: MarkKind(params[0])}
+
+	switch mark.Kind {
+	case MarkPromptStart, MarkCommandStart, MarkCommandExecuted:
+		// options are ignored
+	case MarkCommandFinished:
+		if len(params) >= 2 && params[1] != "" {
+			code, err := strconv.Atoi(params[1])
+			if err != nil {
+				return nil, invalidOperatingSystemCommand
+			}
+
+			mark.ExitCode = &code
+		}
+	case MarkCommandLine:
+		if !vscode {
+			return nil, nil
+		}
+
+		if len(params) >= 2 {
+			mark.CommandLine = unescape633(params[1])
+		}
+	default:
+		return nil, nil
+	}
+
+	return mark, nil
+}
+
+// Marks returns all the shell integration marks in order.
+func (f *EscapeFilter) Marks() []Mark {
+	return eventsOf[Mark](f.events)
+}
+
+// Command represents a command executed in a shell, split by shell integration marks.
+type Command struct {
+	// Row is the row where the prompt starts.
+	Row int `json:"row"`
+	// Prompt is the text of the prompt.
+	Prompt string `json:"prompt"`
+	// CommandLine is the command line, which is reported explicitly or the text input after the prompt.
+	CommandLine string `json:"commandLine"`
+	// Output is the text output by the command.
+	Output string `json:"output"`
+	// ExitCode is the exit code of the command (nil if not reported).
+	ExitCode *int `json:"exitCode"`
+	// Directory is the working directory where the prompt starts (empty if not reported).
+	Directory string `json:"directory,omitempty"`
+}
+
+// textBetween returns the text in the screen from the position to the other (exclusive).
+func (s *Screen) textBetween(from Position, to Position) string {
+	if to.Row < from.Row || (to.Row == from.Row && to.Col <= from.Col) {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	for r := from.Row; r <= to.Row; r++ {
+		if r > from.Row {
+			sb.WriteRune('\n')
+		}
+
+		line := s.line(r)
+
+		if r == to.Row {
+			line = line.truncate(to.Col - 1)
+		}
+
+		if r == from.Row {
+			line = line.truncateLeft(from.Col - 1)
+		}
+
+		sb.WriteString(line.String())
+	}
+
+	return sb.String()
+}
+
+// end returns the position of the end of the screen content.
+func (s *Screen) end() Position {
+	if len(s.lines) == 0 {
+		return Position{Row: s.top + 1, Col: 1}
+	}
+
+	n := s.top + len(s.lines)
+	return Position{Row: n, Col: s.line(n).width() + 1}
+}
+
+// commandMarks stores marks of a command.
+type commandMarks struct {
+	promptStart     *Mark
+	commandStart    *Mark
+	commandExecuted *Mark
+	commandLine     *Mark
+	end             Position
+	exitCode        *int
+	directory       string
+}
+
+// command builds the Command from the marks and the screen content.
+func (cm *commandMarks) command(s *Screen) Command {
+	c := Command{ExitCode: cm.exitCode, Directory: cm.directory}
+
+	if cm.promptStart != nil {
+		c.Row = cm.promptStart.Row
+	}
+
+	if cm.promptStart != nil && cm.commandStart != nil {
+		c.Prompt = s.textBetween(cm.promptStart.Position, cm.commandStart.Position)
+	}
+
+	if cm.commandStart != nil && cm.commandExecuted != nil {
+		c.CommandLine = strings.TrimRight(s.textBetween(cm.commandStart.Position, cm.commandExecuted.Position), " \n")
+	}
+
+	if cm.commandLine != nil {
+		c.CommandLine = cm.commandLine.CommandLine
+	}
+
+	if cm.commandExecuted != nil {
+		c.Output = strings.TrimRight(s.textBetween(cm.commandExecuted.Position, cm.end), "\n")
+	}
+
+	return c
+}
+
+// transcript builds the commands from shell integration marks as they arrive.
+type transcript struct {
+	// commands stores the finished commands.
+	commands []Command
+	// cur stores the marks of the command in progress (nil if none).
+	cur *commandMarks
+	// directory is the last working directory reported.
+	directory string
+}
+
+// flush finishes the command in progress at the position, taking its text from the screen at the moment.
+func (t *transcript) flush(s *Screen, end Position) {
+	if t.cur == nil {
+		return
+	}
+
+	t.cur.end = end
+	t.commands = append(t.commands, t.cur.command(s))
+	t.cur = nil
+}
+
+// add updates the transcript with the event.
+func (t *transcript) add(s *Screen, e Event) {
+	if dc, ok := e.(DirectoryChange); ok {
+		t.directory = dc.Path
+		return
+	}
+
+	m, ok := e.(Mark)
+	if !ok {
+		return
+	}
+	mark := &m
+
+	switch mark.Kind {
+	case MarkPromptStart:
+		t.flush(s, mark.Position)
+		t.cur = &commandMarks{promptStart: mark, directory: t.directory}
+	case MarkCommandStart:
+		if t.cur == nil {
+			t.cur = &commandMarks{}
+		}
+		t.cur.commandStart = mark
+	case MarkCommandExecuted:
+		if t.cur == nil {
+			t.cur = &commandMarks{}
+		}
+		t.cur.commandExecuted = mark
+	case MarkCommandLine:
+		if t.cur == nil {
+			t.cur = &commandMarks{}
+		}
+		t.cur.commandLine = mark
+	case MarkCommandFinished:
+		// D without executing a command (e.g. at the first prompt) is ignored
+		if t.cur != nil && t.cur.commandExecuted != nil {
+			t.cur.exitCode = mark.ExitCode
+			t.flush(s, mark.Position)
+		}
+	}
+}
+
+// Transcript returns the commands split by shell integration marks.
+// A command starts with MarkPromptStart and ends with MarkCommandFinished or the next MarkPromptStart.
+// The text of a command is taken from the screen when the command ends,
+// so the output cleared or overwritten afterwards is kept, but the output cleared by the command itself
+// (or discarded in the streaming mode before the command ends) is not.
+// The text of the unfinished command is taken from the current screen.
+func (f *EscapeFilter) Transcript() []Command {
+	commands := append([]Command(nil), f.transcript.commands...)
+
+	// the last prompt without any command is not included
+	if cur := f.transcript.cur; cur != nil && (cur.commandExecuted != nil || cur.commandLine != nil) {
+		last := *cur
+		last.end = f.screen.end()
+		commands = append(commands, last.command(f.screen))
+	}
+
+	return commands
+}
+
+// WriteTranscriptJSON writes the commands split by shell integration marks to the Writer in JSON.
+func (f *EscapeFilter) WriteTranscriptJSON(w io.Writer) error {
+	commands := f.Transcript()
+	if commands == nil {
+		commands = []Command{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	return enc.Encode(commands)
+}
+
+// WriteTranscriptText writes the commands split by shell integration marks to the Writer in plain text.
+// Each command is written as a block of the prompt with the command line, the output and the exit code.
+func (f *EscapeFilter) WriteTranscriptText(w io.Writer) error {
+	for i, c := range f.Transcript() {
+		var sb strings.Builder
+
+		if i > 0 {
+			sb.WriteRune('\n')
+		}
+
+		sb.WriteString(c.Prompt)
+		sb.WriteString(c.CommandLine)
+		sb.WriteRune('\n')
+
+		if c.Output != "" {
+			sb.WriteString(c.Output)
+			sb.WriteRune('\n')
+		}
+
+		if c.ExitCode != nil {
+			fmt.Fprintf(&sb, "[exit: %d]\n", *c.ExitCode)
+		}
+
+		if _, err := io.WriteString(w, sb.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
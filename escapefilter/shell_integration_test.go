@@ -0,0 +1,208 @@
+package escapefilter
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_unescape633(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected string
+	}{
+		{str: "ls -l", expected: "ls -l"},
+		{str: `echo a\x3bb`, expected: "echo a;b"},
+		{str: `echo a\\b`, expected: `echo a\b`},
+		{str: `echo \x`, expected: `echo \x`},
+		{str: `echo \xzz`, expected: `echo \xzz`},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("str=%q", tt.str), func(t *testing.T) {
+			if actual := unescape633(tt.str); actual != tt.expected {
+				t.Errorf("unescape633() should return %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_parseMark(t *testing.T) {
+	code := 127
+
+	tests := []struct {
+		param   string
+		vscode  bool
+		mark    *Mark
+		isError bool
+	}{
+		{param: "A", mark: &Mark{Kind: MarkPromptStart}},
+		{param: "A;cl=m", mark: &Mark{Kind: MarkPromptStart}},
+		{param: "B", mark: &Mark{Kind: MarkCommandStart}},
+		{param: "C", mark: &Mark{Kind: MarkCommandExecuted}},
+		{param: "D", mark: &Mark{Kind: MarkCommandFinished}},
+		{param: "D;127", mark: &Mark{Kind: MarkCommandFinished, ExitCode: &code}},
+		{param: "D;abc", mark: nil, isError: true},
+		{param: `E;echo a\x3bb`, vscode: true, mark: &Mark{Kind: MarkCommandLine, CommandLine: "echo a;b"}},
+		{param: `E;echo`, vscode: false, mark: nil},
+		{param: "P;Cwd=/tmp", vscode: true, mark: nil},
+		{param: "", mark: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q,vscode=%t", tt.param, tt.vscode), func(t *testing.T) {
+			mark, err := parseMark(Position{}, tt.param, tt.vscode)
+
+			if diff := cmp.Diff(tt.mark, mark); diff != "" {
+				t.Errorf("parseMark() differs from expected\n%s", diff)
+			}
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseMark() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseMark() should return not error, got %#v", err)
+			}
+		})
+	}
+}
+
+func Test_Screen_textBetween(t *testing.T) {
+	s := &Screen{lines: newLines("$ ls", "a.txt  b.txt", "こんにちは"), row: 3, col: 1}
+
+	tests := []struct {
+		from     Position
+		to       Position
+		expected string
+	}{
+		{from: Position{Row: 1, Col: 3}, to: Position{Row: 2, Col: 1}, expected: "ls\n"},
+		{from: Position{Row: 2, Col: 1}, to: Position{Row: 2, Col: 6}, expected: "a.txt"},
+		{from: Position{Row: 2, Col: 8}, to: Position{Row: 4, Col: 1}, expected: "b.txt\nこんにちは\n"},
+		{from: Position{Row: 3, Col: 3}, to: Position{Row: 3, Col: 7}, expected: "んに"},
+		{from: Position{Row: 2, Col: 1}, to: Position{Row: 1, Col: 1}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("from=%v,to=%v", tt.from, tt.to), func(t *testing.T) {
+			if actual := s.textBetween(tt.from, tt.to); actual != tt.expected {
+				t.Errorf("textBetween() should return %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// shellSession is a session recorded from a shell with shell integration.
+var shellSession = strings.Join([]string{
+	"\x1b]133;D\x07\x1b]133;A\x07$ \x1b]133;B\x07ls\r",
+	"\x1b]133;C\x07a.txt  b.txt\r",
+	"\x1b]133;D;0\x07\x1b]133;A\x07$ \x1b]133;B\x07cat c.txt\r",
+	"\x1b]133;C\x07cat: c.txt: No such file or directory\r",
+	"\x1b]133;D;1\x07\x1b]133;A\x07$ \x1b]133;B\x07\x1b]633;E;echo a\\x3bb\x07echo a;b\r",
+	"\x1b]133;C\x07a\r",
+	"b\r",
+	"\x1b]133;D;0\x07\x1b]133;A\x07$ \x1b]133;B\x07",
+}, "\n")
+
+func Test_EscapeFilter_Transcript(t *testing.T) {
+	zero, one := 0, 1
+
+	expected := []Command{
+		{Row: 1, Prompt: "$ ", CommandLine: "ls", Output: "a.txt  b.txt", ExitCode: &zero},
+		{Row: 3, Prompt: "$ ", CommandLine: "cat c.txt", Output: "cat: c.txt: No such file or directory", ExitCode: &one},
+		{Row: 5, Prompt: "$ ", CommandLine: "echo a;b", Output: "a\nb", ExitCode: &zero},
+	}
+
+	filter := New()
+	filter.Load(strings.NewReader(shellSession))
+
+	if diff := cmp.Diff(expected, filter.Transcript()); diff != "" {
+		t.Errorf("Transcript() differs from expected\n%s", diff)
+	}
+
+	if n := len(filter.Marks()); n != 16 {
+		t.Errorf("Marks() should return %d marks, got %d", 16, n)
+	}
+}
+
+func Test_EscapeFilter_Transcript_Unfinished(t *testing.T) {
+	source := "\x1b]133;A\x07$ \x1b]133;B\x07sleep 10\r\n\x1b]133;C\x07zzz"
+
+	expected := []Command{
+		{Row: 1, Prompt: "$ ", CommandLine: "sleep 10", Output: "zzz"},
+	}
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if diff := cmp.Diff(expected, filter.Transcript()); diff != "" {
+		t.Errorf("Transcript() differs from expected\n%s", diff)
+	}
+}
+
+func Test_EscapeFilter_Transcript_Cleared(t *testing.T) {
+	zero := 0
+	source := "\x1b]133;A\x07$ \x1b]133;B\x07ls\r\n\x1b]133;C\x07a.txt\r\n\x1b]133;D;0\x07" +
+		"\x1b]133;A\x07$ \x1b]133;B\x07clear\r\n\x1b]133;C\x07\x1b[H\x1b[2J\x1b]133;D;0\x07"
+
+	expected := []Command{
+		{Row: 1, Prompt: "$ ", CommandLine: "ls", Output: "a.txt", ExitCode: &zero},
+		{Row: 3, ExitCode: &zero},
+	}
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if diff := cmp.Diff(expected, filter.Transcript()); diff != "" {
+		t.Errorf("Transcript() differs from expected\n%s", diff)
+	}
+}
+
+func Test_EscapeFilter_WriteTranscriptText(t *testing.T) {
+	expected := strings.Join([]string{
+		"$ ls",
+		"a.txt  b.txt",
+		"[exit: 0]",
+		"",
+		"$ cat c.txt",
+		"cat: c.txt: No such file or directory",
+		"[exit: 1]",
+		"",
+		"$ echo a;b",
+		"a",
+		"b",
+		"[exit: 0]",
+		"",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(shellSession))
+
+	var buf bytes.Buffer
+	if err := filter.WriteTranscriptText(&buf); err != nil {
+		t.Fatalf("WriteTranscriptText() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteTranscriptText() should write %q, got %q", expected, actual)
+	}
+}
+
+func Test_EscapeFilter_WriteTranscriptJSON(t *testing.T) {
+	source := "\x1b]133;A\x07$ \x1b]133;B\x07true\r\n\x1b]133;C\x07\x1b]133;D;0\x07"
+	expected := `[{"row":1,"prompt":"$ ","commandLine":"true","output":"","exitCode":0}]` + "\n"
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := filter.WriteTranscriptJSON(&buf); err != nil {
+		t.Fatalf("WriteTranscriptJSON() should not return error, got %v", err)
+	}
+
+	if actual := buf.String(); actual != expected {
+		t.Errorf("WriteTranscriptJSON() should write %q, got %q", expected, actual)
+	}
+}
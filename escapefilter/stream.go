@@ -0,0 +1,124 @@
+package escapefilter
+
+import (
+	"io"
+	"strings"
+)
+
+// DefaultLookback is the default number of rows which can still be changed in the streaming mode,
+// which is the height of the default screen.
+const DefaultLookback = defaultScreenRows
+
+// StreamOptions represents options of the streaming mode.
+type StreamOptions struct {
+	// TextOptions specifies how the lines are rendered.
+	TextOptions
+
+	// Lookback is the number of rows from the bottom of the screen which can still be changed.
+	// Rows above them are final: they are written out, and the cursor can no longer reach them.
+	// DefaultLookback is used if Lookback <= 0.
+	Lookback int
+}
+
+// stream stores the state of the streaming mode.
+type stream struct {
+	w    io.Writer
+	opts StreamOptions
+
+	// fn stores hyperlinks numbered so far, which are listed at the end.
+	fn footnotes
+
+	// annotations stores annotations not written yet, sorted by row.
+	annotations []annotation
+
+	// err is the error in writing rows discarded by MaxRows, which is returned by the next flushStream.
+	err error
+}
+
+// Stream enables the streaming mode, in which final rows are written to the Writer in plain text
+// and removed from the screen as soon as possible, so that memory usage is bounded.
+// For the same reason, events are dropped as soon as they are converted into annotations,
+// and shell integration marks are not built into commands,
+// so that Events (and Marks, Transcript etc.) don't return the events in the streaming mode.
+// The rest of the screen is written by Close (not by Load), which ends the streaming mode.
+// The whole output is the same as Text, unless the cursor goes above the lookback rows.
+func (f *EscapeFilter) Stream(w io.Writer, opts StreamOptions) {
+	if opts.Lookback <= 0 {
+		opts.Lookback = DefaultLookback
+	}
+
+	f.stream = &stream{w: w, opts: opts}
+	f.collectStream()
+
+	// rows discarded by MaxRows are written out before they are lost
+	f.screen.discarding = func(row int) {
+		if err := f.writeStream(row); err != nil && f.stream.err == nil {
+			f.stream.err = err
+		}
+	}
+}
+
+// collectStream converts the events added since the last call into annotations, and drops the events.
+func (f *EscapeFilter) collectStream() {
+	st := f.stream
+
+	if len(f.events) == 0 {
+		return
+	}
+
+	st.annotations = append(st.annotations, annotations(f.events, st.opts.TextOptions)...)
+	f.events = nil
+
+	// annotations are sorted by row in each call
+	sortAnnotations(st.annotations)
+}
+
+// flushStream writes the final rows to the Writer and removes them from the screen.
+func (f *EscapeFilter) flushStream() error {
+	if err := f.stream.err; err != nil {
+		f.stream.err = nil
+		return err
+	}
+
+	return f.writeStream(f.screen.rows() - f.stream.opts.Lookback)
+}
+
+// writeStream writes the rows up to the row to the Writer and removes them from the screen.
+func (f *EscapeFilter) writeStream(last int) error {
+	st := f.stream
+	s := f.screen
+
+	if last <= s.top {
+		return nil
+	}
+
+	f.collectStream()
+
+	var sb strings.Builder
+
+	for r := s.top + 1; r <= last; r++ {
+		st.annotations = writeAnnotations(&sb, st.annotations, r)
+		s.writeLine(&sb, r, st.opts.TextOptions, &st.fn)
+		sb.WriteRune('\n')
+	}
+
+	s.discard(last)
+
+	_, err := io.WriteString(st.w, sb.String())
+	return err
+}
+
+// closeStream writes the rest of the screen to the Writer, and ends the streaming mode.
+func (f *EscapeFilter) closeStream() error {
+	f.collectStream()
+
+	st := f.stream
+	f.stream = nil
+	f.screen.discarding = nil
+
+	var sb strings.Builder
+	f.screen.writeText(&sb, st.opts.TextOptions, st.annotations, &st.fn)
+
+	_, err := io.WriteString(st.w, sb.String())
+	return err
+}
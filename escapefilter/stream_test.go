@@ -0,0 +1,148 @@
+package escapefilter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_EscapeFilter_Stream(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b]2;first\x07line 1",
+		"\x1b]8;;https://example.com/\x07link\x1b]8;;\x07 line 2",
+		"\x1b#6wide line 3",
+		"line 4\x1b]9;done\x07",
+		"progress 10%\rprogress 50%\rprogress 100%",
+		"\x1b]2;second\x07\x1b]8;;https://example.com/\x07again\x1b]8;;\x07",
+		"\x1b]8;;https://example.org/\x07other\x1b]8;;\x07",
+		"line 8\x1b[Aup\x1b[B",
+		"   ",
+	}, "\n")
+
+	opts := TextOptions{
+		ExpandDoubleWidth:   true,
+		Links:               LinkFootnote,
+		TitleMarkers:        true,
+		NotificationMarkers: true,
+	}
+
+	expected := New()
+	expected.Load(strings.NewReader(source))
+
+	for _, lookback := range []int{2, 3, 0} {
+		t.Run(fmt.Sprintf("lookback=%d", lookback), func(t *testing.T) {
+			var sb strings.Builder
+
+			filter := New()
+			filter.Stream(&sb, StreamOptions{TextOptions: opts, Lookback: lookback})
+
+			max := lookback
+			if max <= 0 {
+				max = DefaultLookback
+			}
+
+			for i := 0; i < len(source); i++ {
+				filter.Write([]byte{source[i]})
+
+				if n := len(filter.screen.lines); n > max {
+					t.Fatalf("screen should have at most %d lines, got %d", max, n)
+				}
+			}
+
+			if err := filter.Close(); err != nil {
+				t.Fatalf("Close() should not return error, got %v", err)
+			}
+
+			if actual := sb.String(); actual != expected.Text(opts) {
+				t.Errorf("output should be %q, got %q", expected.Text(opts), actual)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_Stream_Events(t *testing.T) {
+	var sb strings.Builder
+
+	filter := New()
+	filter.Stream(&sb, StreamOptions{Lookback: 2})
+
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(filter, "\x1b]133;A\x07$ \x1b]133;B\x07make\r\n\x1b]133;C\x07\x1b]9;4;1;%d\x07line %d\r\n\x1b]133;D;0\x07", i%100, i)
+
+		// events on the lookback rows are kept until the rows are written
+		if n := len(filter.events); n > 8 {
+			t.Fatalf("filter should keep at most %d events, got %d", 8, n)
+		}
+	}
+
+	if n := len(filter.transcript.commands); n != 0 {
+		t.Errorf("filter should keep no commands, got %d", n)
+	}
+
+	if err := filter.Close(); err != nil {
+		t.Fatalf("Close() should not return error, got %v", err)
+	}
+}
+
+func Test_EscapeFilter_Stream_Lookback(t *testing.T) {
+	var sb strings.Builder
+
+	filter := New()
+	filter.Stream(&sb, StreamOptions{Lookback: 2})
+	filter.Load(strings.NewReader("1\n2\n3\n4\x1b[1;1HX"))
+
+	if actual := sb.String(); actual != "1\n2\n" {
+		t.Errorf("output should be %q, got %q", "1\n2\n", actual)
+	}
+
+	filter.Close()
+
+	if actual := sb.String(); actual != "1\n2\nX\n4" {
+		t.Errorf("output should be %q, got %q", "1\n2\nX\n4", actual)
+	}
+
+	// the streaming mode ends by Close
+	filter.Write([]byte("5"))
+	if actual := sb.String(); actual != "1\n2\nX\n4" {
+		t.Errorf("output should be %q, got %q", "1\n2\nX\n4", actual)
+	}
+}
+
+func Test_EscapeFilter_Stream_MaxRows(t *testing.T) {
+	var sb strings.Builder
+
+	filter := New()
+	filter.SetLimits(Limits{MaxRows: 2})
+	filter.Stream(&sb, StreamOptions{})
+	filter.Load(strings.NewReader("1\n2\n3\n4\x1b[1;1HX"))
+
+	// rows discarded by MaxRows are written out even within the lookback rows
+	if actual := sb.String(); actual != "1\n2\n" {
+		t.Errorf("output should be %q, got %q", "1\n2\n", actual)
+	}
+
+	filter.Close()
+
+	if actual := sb.String(); actual != "1\n2\nX\n4" {
+		t.Errorf("output should be %q, got %q", "1\n2\nX\n4", actual)
+	}
+}
+
+type errorWriter struct {
+	err error
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	return 0, w.err
+}
+
+func Test_EscapeFilter_Stream_Error(t *testing.T) {
+	w := &errorWriter{err: fmt.Errorf("write error")}
+
+	filter := New()
+	filter.Stream(w, StreamOptions{Lookback: 1})
+
+	if n, err := filter.Write([]byte("a\nb\nc")); n != 2 || err != w.err {
+		t.Errorf("Write() should return (2, %v), got (%d, %v)", w.err, n, err)
+	}
+}
@@ -0,0 +1,172 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/mattn/go-runewidth"
+	"strings"
+)
+
+// LinkStyle represents how hyperlinks are rendered in plain text.
+type LinkStyle int
+
+// Link styles
+const (
+	// LinkPlain outputs only the text of hyperlinks.
+	LinkPlain LinkStyle = iota
+	// LinkFootnote appends a number to the text of each hyperlink, and lists the URIs at the end.
+	LinkFootnote
+)
+
+// TextOptions represents options to render the screen as plain text.
+type TextOptions struct {
+	// ExpandDoubleWidth inserts spaces between characters in double-width (and double-height) lines.
+	// Characters are output as they are otherwise.
+	ExpandDoubleWidth bool
+
+	// Links specifies how hyperlinks are rendered.
+	Links LinkStyle
+
+	// TitleMarkers inserts a line like "[title: ...]" before the line where the window title is changed.
+	// This is effective only in EscapeFilter.Text.
+	TitleMarkers bool
+
+	// NotificationMarkers inserts a line like "[notify: ...]" before the line where a desktop notification is sent.
+	// This is effective only in EscapeFilter.Text.
+	NotificationMarkers bool
+}
+
+// annotation represents a line inserted into plain text, before the line at the row.
+type annotation struct {
+	row  int
+	text string
+}
+
+// footnotes stores hyperlinks numbered in order of appearance.
+type footnotes struct {
+	links []*Hyperlink
+}
+
+// number returns the number of the hyperlink, adding it if not appeared yet.
+// Hyperlinks with the same ID and URI share the same number.
+func (fn *footnotes) number(link *Hyperlink) int {
+	for i, l := range fn.links {
+		if *l == *link {
+			return i + 1
+		}
+	}
+
+	fn.links = append(fn.links, link)
+	return len(fn.links)
+}
+
+// String returns the list of the numbered URIs.
+func (fn *footnotes) String() string {
+	var sb strings.Builder
+
+	for i, link := range fn.links {
+		fmt.Fprintf(&sb, "[%d] %s\n", i+1, link.URI)
+	}
+
+	return sb.String()
+}
+
+// Text returns string content of the screen rendered with the options.
+// If the cursor is farther than the end of the content, additional lines and spaces will be added.
+func (s *Screen) Text(opts TextOptions) string {
+	return s.text(opts, nil)
+}
+
+// text returns string content of the screen rendered with the options and the annotations.
+// Annotations must be sorted by row. Annotations beyond the last row are output at the end.
+func (s *Screen) text(opts TextOptions, annotations []annotation) string {
+	var sb strings.Builder
+	var fn footnotes
+
+	s.writeText(&sb, opts, annotations, &fn)
+
+	return sb.String()
+}
+
+// writeText writes string content of the screen rendered with the options and the annotations,
+// followed by the footnotes.
+func (s *Screen) writeText(sb *strings.Builder, opts TextOptions, annotations []annotation, fn *footnotes) {
+	for r := s.top + 1; r <= s.rows(); r++ {
+		if r > s.top+1 {
+			sb.WriteRune('\n')
+		}
+
+		annotations = writeAnnotations(sb, annotations, r)
+		s.writeLine(sb, r, opts, fn)
+	}
+
+	for _, a := range annotations {
+		sb.WriteRune('\n')
+		sb.WriteString(a.text)
+	}
+
+	if len(fn.links) > 0 {
+		sb.WriteString("\n\n")
+		sb.WriteString(strings.TrimSuffix(fn.String(), "\n"))
+	}
+}
+
+// writeAnnotations writes the annotations before the line at the row, and returns the rest of them.
+func writeAnnotations(sb *strings.Builder, annotations []annotation, row int) []annotation {
+	for len(annotations) > 0 && annotations[0].row <= row {
+		sb.WriteString(annotations[0].text)
+		sb.WriteRune('\n')
+		annotations = annotations[1:]
+	}
+
+	return annotations
+}
+
+// writeLine writes the line at the row rendered with the options.
+// Hyperlinks are numbered by the footnotes if needed.
+func (s *Screen) writeLine(sb *strings.Builder, r int, opts TextOptions, fn *footnotes) {
+	if r > s.top+len(s.lines) {
+		return
+	}
+
+	expand := opts.ExpandDoubleWidth && s.LineRendition(r) != SingleWidth
+
+	line := s.line(r).String()
+	if expand {
+		line = expandDoubleWidth(line)
+	}
+
+	if opts.Links == LinkFootnote && s.hasHyperlinks(r) {
+		w := 0
+		for _, sp := range s.spans(r) {
+			text := sp.text
+			if expand {
+				// spaces between spans are also needed
+				sb.WriteString(strings.Repeat(" ", w))
+				text = expandDoubleWidth(text)
+
+				runes := []rune(sp.text)
+				w = runewidth.RuneWidth(runes[len(runes)-1])
+			}
+
+			sb.WriteString(text)
+
+			if sp.link != nil {
+				fmt.Fprintf(sb, "[%d]", fn.number(sp.link))
+			}
+		}
+	} else {
+		sb.WriteString(line)
+	}
+
+	// footnote numbers are not counted in padding
+	if r == s.row {
+		width := s.col - 1
+		if expand {
+			width *= 2
+		}
+
+		if pad := width - runewidth.StringWidth(line); pad > 0 {
+			sb.WriteString(strings.Repeat(" ", pad))
+		}
+	}
+}
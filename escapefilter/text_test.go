@@ -0,0 +1,138 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/andreyvit/diff"
+	"strings"
+	"testing"
+)
+
+func Test_Screen_Text(t *testing.T) {
+	tests := []struct {
+		screen   *Screen
+		opts     TextOptions
+		expected string
+	}{
+		{
+			screen:   &Screen{row: 1, col: 1},
+			opts:     TextOptions{},
+			expected: "",
+		},
+		{
+			screen:   &Screen{row: 3, col: 1},
+			opts:     TextOptions{},
+			expected: "\n\n",
+		},
+		{
+			screen:   &Screen{lines: newLines("Hello", "World"), row: 2, col: 8},
+			opts:     TextOptions{},
+			expected: "Hello\nWorld  ",
+		},
+		{
+			screen:   &Screen{lines: newLines("Hello", "World"), row: 2, col: 3, renditions: map[int]LineRendition{1: DoubleWidth}},
+			opts:     TextOptions{},
+			expected: "Hello\nWorld",
+		},
+		{
+			screen:   &Screen{lines: newLines("Hello", "World"), row: 2, col: 3, renditions: map[int]LineRendition{1: DoubleWidth}},
+			opts:     TextOptions{ExpandDoubleWidth: true},
+			expected: "H e l l o\nWorld",
+		},
+		{
+			screen:   &Screen{lines: newLines("Hello", "World"), row: 2, col: 8, renditions: map[int]LineRendition{2: DoubleHeightBottom}},
+			opts:     TextOptions{ExpandDoubleWidth: true},
+			expected: "Hello\nW o r l d     ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("lines=%q,row=%d,col=%d,opts=%+v", tt.screen.lines, tt.screen.row, tt.screen.col, tt.opts), func(t *testing.T) {
+			if actual := tt.screen.Text(tt.opts); actual != tt.expected {
+				t.Errorf("Text() differs from expected\n%v", diff.LineDiff(tt.expected, actual))
+			}
+		})
+	}
+}
+
+func Test_Screen_Text_LinkFootnote(t *testing.T) {
+	foo := &Hyperlink{URI: "https://example.com/foo"}
+	bar := &Hyperlink{URI: "https://example.com/bar"}
+	foo2 := &Hyperlink{URI: "https://example.com/foo"}
+
+	s := &Screen{
+		lines:      newLines("foo and bar", "", "foo again", "Wide foo"),
+		row:        4,
+		col:        12,
+		links:      [][]*Hyperlink{{foo, foo, foo, nil, nil, nil, nil, nil, bar, bar, bar}, nil, {foo2, foo2, foo2}, {nil, nil, nil, nil, nil, foo, foo, foo}},
+		renditions: map[int]LineRendition{4: DoubleWidth},
+	}
+
+	tests := []struct {
+		opts     TextOptions
+		expected string
+	}{
+		{
+			opts:     TextOptions{},
+			expected: "foo and bar\n\nfoo again\nWide foo   ",
+		},
+		{
+			opts: TextOptions{Links: LinkFootnote},
+			expected: strings.Join([]string{
+				"foo[1] and bar[2]",
+				"",
+				"foo[1] again",
+				"Wide foo[1]   ",
+				"",
+				"[1] https://example.com/foo",
+				"[2] https://example.com/bar",
+			}, "\n"),
+		},
+		{
+			opts: TextOptions{Links: LinkFootnote, ExpandDoubleWidth: true},
+			expected: strings.Join([]string{
+				"foo[1] and bar[2]",
+				"",
+				"foo[1] again",
+				"W i d e   f o o[1]       ",
+				"",
+				"[1] https://example.com/foo",
+				"[2] https://example.com/bar",
+			}, "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("opts=%+v", tt.opts), func(t *testing.T) {
+			if actual := s.Text(tt.opts); actual != tt.expected {
+				t.Errorf("Text() differs from expected\n%v", diff.LineDiff(tt.expected, actual))
+			}
+		})
+	}
+}
+
+func Test_Screen_text_Annotations(t *testing.T) {
+	s := &Screen{lines: newLines("foo", "bar"), row: 3, col: 1}
+
+	annotations := []annotation{
+		{row: 1, text: "[a]"},
+		{row: 2, text: "[b]"},
+		{row: 2, text: "[c]"},
+		{row: 3, text: "[d]"},
+		{row: 5, text: "[e]"},
+	}
+
+	expected := strings.Join([]string{
+		"[a]",
+		"foo",
+		"[b]",
+		"[c]",
+		"bar",
+		"[d]",
+		"",
+		"[e]",
+	}, "\n")
+
+	if actual := s.text(TextOptions{}, annotations); actual != expected {
+		t.Errorf("text() differs from expected\n%v", diff.LineDiff(expected, actual))
+	}
+}
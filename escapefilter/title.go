@@ -0,0 +1,101 @@
+package escapefilter
+
+// maxTitleStack is the maximum depth of the title stack, which is the same as xterm.
+const maxTitleStack = 10
+
+// TitleChange represents a change of the window title and/or the icon name.
+type TitleChange struct {
+	Position
+
+	// Window reports whether the window title is changed.
+	Window bool `json:"window"`
+	// Icon reports whether the icon name is changed.
+	Icon bool `json:"icon"`
+	// Title is the new window title or icon name.
+	Title string `json:"title"`
+}
+
+// titleState stores the window title and the icon name.
+type titleState struct {
+	title    string
+	iconName string
+}
+
+// titles stores the current window title and the icon name, with the stack.
+type titles struct {
+	titleState
+
+	stack []titleState
+}
+
+// set changes the window title and/or the icon name, and returns the change.
+func (t *titles) set(pos Position, window bool, icon bool, title string) TitleChange {
+	if window {
+		t.title = title
+	}
+
+	if icon {
+		t.iconName = title
+	}
+
+	return TitleChange{
+		Position: pos,
+		Window:   window,
+		Icon:     icon,
+		Title:    title,
+	}
+}
+
+// push saves the current window title and icon name onto the stack.
+// The oldest one is discarded if the stack is full.
+func (t *titles) push() {
+	if len(t.stack) >= maxTitleStack {
+		t.stack = t.stack[1:]
+	}
+
+	t.stack = append(t.stack, t.titleState)
+}
+
+// pop restores the window title and/or the icon name from the stack, and returns the changes.
+// Parts which are restored to the same value as the current one are not reported as changes.
+func (t *titles) pop(pos Position, window bool, icon bool) []TitleChange {
+	if len(t.stack) == 0 {
+		return nil
+	}
+
+	st := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	window = window && st.title != t.title
+	icon = icon && st.iconName != t.iconName
+
+	if window && icon && st.title != st.iconName {
+		return []TitleChange{
+			t.set(pos, true, false, st.title),
+			t.set(pos, false, true, st.iconName),
+		}
+	}
+
+	if window {
+		return []TitleChange{t.set(pos, true, icon, st.title)}
+	} else if icon {
+		return []TitleChange{t.set(pos, false, true, st.iconName)}
+	}
+
+	return nil
+}
+
+// Title returns the current window title.
+func (f *EscapeFilter) Title() string {
+	return f.titles.title
+}
+
+// IconName returns the current icon name.
+func (f *EscapeFilter) IconName() string {
+	return f.titles.iconName
+}
+
+// TitleHistory returns all the changes of the window title and the icon name in order.
+func (f *EscapeFilter) TitleHistory() []TitleChange {
+	return eventsOf[TitleChange](f.events)
+}
@@ -0,0 +1,149 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/andreyvit/diff"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_titles_pop(t *testing.T) {
+	tests := []struct {
+		current  titleState
+		saved    titleState
+		window   bool
+		icon     bool
+		expected []TitleChange
+	}{
+		{
+			saved:  titleState{title: "title", iconName: "icon"},
+			window: true,
+			icon:   true,
+			expected: []TitleChange{
+				{Window: true, Title: "title"},
+				{Icon: true, Title: "icon"},
+			},
+		},
+		{
+			saved:    titleState{title: "title", iconName: "icon"},
+			window:   true,
+			icon:     false,
+			expected: []TitleChange{{Window: true, Title: "title"}},
+		},
+		{
+			saved:    titleState{title: "title", iconName: "icon"},
+			window:   false,
+			icon:     true,
+			expected: []TitleChange{{Icon: true, Title: "icon"}},
+		},
+		{
+			saved:    titleState{title: "title", iconName: "title"},
+			window:   true,
+			icon:     true,
+			expected: []TitleChange{{Window: true, Icon: true, Title: "title"}},
+		},
+		{
+			current:  titleState{title: "changed"},
+			saved:    titleState{title: "title"},
+			window:   true,
+			icon:     true,
+			expected: []TitleChange{{Window: true, Title: "title"}},
+		},
+		{
+			current:  titleState{title: "title", iconName: "changed"},
+			saved:    titleState{title: "title", iconName: "icon"},
+			window:   true,
+			icon:     true,
+			expected: []TitleChange{{Icon: true, Title: "icon"}},
+		},
+		{
+			current:  titleState{title: "title", iconName: "icon"},
+			saved:    titleState{title: "title", iconName: "icon"},
+			window:   true,
+			icon:     true,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("current=%+v,saved=%+v,window=%t,icon=%t", tt.current, tt.saved, tt.window, tt.icon), func(t *testing.T) {
+			ts := &titles{titleState: tt.current, stack: []titleState{tt.saved}}
+			changes := ts.pop(Position{}, tt.window, tt.icon)
+
+			if diff := cmp.Diff(tt.expected, changes); diff != "" {
+				t.Errorf("changes differ from expected\n%s", diff)
+			}
+
+			if len(ts.stack) != 0 {
+				t.Errorf("stack should be empty, got %v", ts.stack)
+			}
+		})
+	}
+}
+
+func Test_titles_push(t *testing.T) {
+	ts := &titles{}
+
+	for i := 1; i <= maxTitleStack+2; i++ {
+		ts.title = fmt.Sprintf("title%d", i)
+		ts.push()
+	}
+
+	if len(ts.stack) != maxTitleStack {
+		t.Fatalf("stack should have length %d, got %d", maxTitleStack, len(ts.stack))
+	}
+
+	if title := ts.stack[0].title; title != "title3" {
+		t.Errorf("the oldest title should be %q, got %q", "title3", title)
+	}
+}
+
+func Test_EscapeFilter_Title(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b]0;shell\x07$ make",
+		"\x1b[22;0t\x1b]2;make: compile\x07compiling",
+		"\x1b]1;make\x07\x1b]2;make: link\x1b\\linking",
+		"\x1b[23;0tdone",
+		"",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if title := filter.Title(); title != "shell" {
+		t.Errorf("Title() should return %q, got %q", "shell", title)
+	}
+
+	if iconName := filter.IconName(); iconName != "shell" {
+		t.Errorf("IconName() should return %q, got %q", "shell", iconName)
+	}
+
+	expected := []TitleChange{
+		{Position: Position{Row: 1, Col: 1}, Window: true, Icon: true, Title: "shell"},
+		{Position: Position{Row: 2, Col: 1}, Window: true, Title: "make: compile"},
+		{Position: Position{Row: 3, Col: 1}, Icon: true, Title: "make"},
+		{Position: Position{Row: 3, Col: 1}, Window: true, Title: "make: link"},
+		{Position: Position{Row: 4, Col: 1}, Window: true, Icon: true, Title: "shell"},
+	}
+
+	if diff := cmp.Diff(expected, filter.TitleHistory()); diff != "" {
+		t.Errorf("TitleHistory() differs from expected\n%s", diff)
+	}
+
+	expectedText := strings.Join([]string{
+		"[title: shell]",
+		"$ make",
+		"[title: make: compile]",
+		"compiling",
+		"[title: make: link]",
+		"linking",
+		"[title: shell]",
+		"done",
+		"",
+	}, "\n")
+
+	if actual := filter.Text(TextOptions{TitleMarkers: true}); actual != expectedText {
+		t.Errorf("Text() differs from expected\n%v", diff.LineDiff(expectedText, actual))
+	}
+}
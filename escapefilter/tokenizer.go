@@ -0,0 +1,309 @@
+package escapefilter
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TokenKind represents the kind of a token.
+type TokenKind int
+
+// Kinds of tokens
+const (
+	// TokenText is a run of graphic characters.
+	TokenText TokenKind = iota
+	// TokenControl is a C0 or C1 control character.
+	TokenControl
+	// TokenEscape is an escape sequence other than CSI, OSC, DCS, SOS, PM and APC.
+	TokenEscape
+	// TokenCSI is a control sequence.
+	TokenCSI
+	// TokenOSC is an operating system command.
+	TokenOSC
+	// TokenDCS is a device control string.
+	TokenDCS
+	// TokenString is a SOS, PM or APC string.
+	TokenString
+)
+
+// String returns the name of the token kind.
+func (k TokenKind) String() string {
+	switch k {
+	case TokenText:
+		return "text"
+	case TokenControl:
+		return "control"
+	case TokenEscape:
+		return "escape"
+	case TokenCSI:
+		return "csi"
+	case TokenOSC:
+		return "osc"
+	case TokenDCS:
+		return "dcs"
+	case TokenString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// Token represents a piece of terminal output: a text run, a control character or a sequence.
+type Token struct {
+	Kind TokenKind
+
+	// Start is the byte offset of the token in the input.
+	Start int64
+	// End is the byte offset just after the token in the input.
+	End int64
+
+	// Text is the characters (TokenText) or the control character (TokenControl).
+	Text string
+
+	// Private is the private marker ("<", "=", ">" or "?") of TokenCSI and TokenDCS (empty if none).
+	Private string
+	// Params is the parameters of TokenCSI and TokenDCS.
+	// Each parameter is a list of the value and its sub-parameters (separated by ":"), and omitted values are -1.
+	Params [][]int
+	// Intermediate is the intermediate bytes of TokenEscape, TokenCSI and TokenDCS.
+	Intermediate string
+	// Final is the final byte of TokenEscape, TokenCSI and TokenDCS.
+	Final string
+
+	// Command is the command of TokenOSC, e.g. "0", "8" or "L".
+	Command string
+	// Introducer is the final character of the introducer of TokenString: "X" (SOS), "^" (PM) or "_" (APC).
+	Introducer string
+	// Data is the parameter string of TokenOSC, or the data of TokenDCS and TokenString.
+	Data string
+	// Terminator is the terminator of TokenOSC, TokenDCS and TokenString: BEL, ST ("\x1b\\") or 8-bit ST.
+	Terminator string
+}
+
+// Param returns the value of the i-th parameter (0-based), or the default value if it is omitted.
+func (t *Token) Param(i int, def int) int {
+	if i < 0 || len(t.Params) <= i || t.Params[i][0] < 0 {
+		return def
+	}
+
+	return t.Params[i][0]
+}
+
+// parseParams splits the parameter bytes of a control sequence into the private marker and the parameters.
+func parseParams(str string) (private string, params [][]int) {
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return r < '<' || '?' < r
+	})
+	if i < 0 {
+		return str, nil
+	}
+
+	private = str[:i]
+
+	for _, param := range strings.Split(str[i:], ";") {
+		var values []int
+
+		for _, sub := range strings.Split(param, ":") {
+			if sub == "" {
+				values = append(values, -1)
+				continue
+			}
+
+			v, err := strconv.Atoi(sub)
+			if err != nil {
+				// too large
+				v = math.MaxInt
+			}
+
+			values = append(values, v)
+		}
+
+		params = append(params, values)
+	}
+
+	return private, params
+}
+
+// escapeSequenceToken returns the token of the escape sequence.
+func escapeSequenceToken(es *escapeSequence) Token {
+	return Token{Kind: TokenEscape, Intermediate: es.intermediate, Final: es.final}
+}
+
+// controlSequenceToken returns the token of the control sequence.
+func controlSequenceToken(cs *controlSequence) Token {
+	private, params := parseParams(cs.param)
+
+	return Token{Kind: TokenCSI, Private: private, Params: params, Intermediate: cs.intermediate, Final: cs.final}
+}
+
+// operatingSystemCommandToken returns the token of the operating system command.
+func operatingSystemCommandToken(osc *operatingSystemCommand) Token {
+	return Token{Kind: TokenOSC, Command: osc.command, Data: osc.param, Terminator: osc.final}
+}
+
+// Tokenizer splits terminal output into tokens without emulating the screen.
+// The input is decoded as UTF-8, or as ISO 8859-1 after ESC % @ (until ESC % G).
+// Invalid sequences and an incomplete sequence at the end yield no tokens.
+type Tokenizer struct {
+	rd     *bufio.Reader
+	parser *parser
+	utf8   bool
+
+	// text is the text run being read.
+	text strings.Builder
+	// textStart and textEnd are the byte offsets of the text run.
+	textStart int64
+	textEnd   int64
+
+	tokens []Token
+	token  Token
+	err    error
+}
+
+// NewTokenizer returns a new Tokenizer reading from the Reader.
+func NewTokenizer(rd io.Reader) *Tokenizer {
+	t := &Tokenizer{rd: bufio.NewReader(rd), utf8: true}
+	t.parser = newParser(t)
+
+	return t
+}
+
+// Scan advances the Tokenizer to the next token, which will be available by Token.
+// Scan returns false at the end of the input or on an error, which will be available by Err.
+func (t *Tokenizer) Scan() bool {
+	for len(t.tokens) == 0 {
+		if t.err != nil {
+			return false
+		}
+
+		r, s, err := readRune(t.rd, t.utf8)
+		if s == 0 {
+			t.err = err
+			t.flushText()
+			continue
+		}
+
+		if err := t.parser.advance(r, s); err != nil {
+			t.err = err
+		}
+	}
+
+	t.token = t.tokens[0]
+	t.tokens = t.tokens[1:]
+
+	return true
+}
+
+// Token returns the token read by the last call of Scan.
+func (t *Tokenizer) Token() Token {
+	return t.token
+}
+
+// Err returns the error occurred while reading the input (nil at the end of the input).
+func (t *Tokenizer) Err() error {
+	if t.err == io.EOF {
+		return nil
+	}
+
+	return t.err
+}
+
+// flushText adds the text run being read as a token.
+func (t *Tokenizer) flushText() {
+	if t.text.Len() == 0 {
+		return
+	}
+
+	t.tokens = append(t.tokens, Token{
+		Kind:  TokenText,
+		Start: t.textStart,
+		End:   t.textEnd,
+		Text:  t.text.String(),
+	})
+	t.text.Reset()
+}
+
+// add adds the token of the sequence ending at the current character.
+func (t *Tokenizer) add(token Token) error {
+	t.flushText()
+
+	token.Start = t.parser.start
+	token.End = t.parser.next
+	t.tokens = append(t.tokens, token)
+
+	return nil
+}
+
+// print appends the character to the text run.
+func (t *Tokenizer) print(r rune) error {
+	if t.text.Len() == 0 {
+		t.textStart = t.parser.offset
+	}
+
+	t.text.WriteRune(r)
+	t.textEnd = t.parser.next
+
+	return nil
+}
+
+// execute adds the token of the control character.
+func (t *Tokenizer) execute(r rune) error {
+	t.flushText()
+
+	t.tokens = append(t.tokens, Token{
+		Kind:  TokenControl,
+		Start: t.parser.offset,
+		End:   t.parser.next,
+		Text:  string(r),
+	})
+
+	return nil
+}
+
+// dispatchEscapeSequence adds the token of the escape sequence.
+func (t *Tokenizer) dispatchEscapeSequence(es *escapeSequence) error {
+	// the coding system affects how to read the input
+	if es.intermediate == "%" {
+		switch es.final {
+		case "G":
+			t.utf8 = true
+		case "@":
+			t.utf8 = false
+		}
+	}
+
+	return t.add(escapeSequenceToken(es))
+}
+
+// dispatchControlSequence adds the token of the control sequence.
+func (t *Tokenizer) dispatchControlSequence(cs *controlSequence) error {
+	return t.add(controlSequenceToken(cs))
+}
+
+// dispatchOperatingSystemCommand adds the token of the operating system command.
+func (t *Tokenizer) dispatchOperatingSystemCommand(osc *operatingSystemCommand) error {
+	return t.add(operatingSystemCommandToken(osc))
+}
+
+// dispatchDeviceControlString adds the token of the device control string.
+func (t *Tokenizer) dispatchDeviceControlString(dcs *deviceControlString) error {
+	private, params := parseParams(dcs.param)
+
+	return t.add(Token{
+		Kind:         TokenDCS,
+		Private:      private,
+		Params:       params,
+		Intermediate: dcs.intermediate,
+		Final:        dcs.final,
+		Data:         dcs.data,
+		Terminator:   dcs.terminator,
+	})
+}
+
+// dispatchControlString adds the token of the SOS, PM or APC string.
+func (t *Tokenizer) dispatchControlString(cs *controlString) error {
+	return t.add(Token{Kind: TokenString, Introducer: cs.introducer, Data: cs.data, Terminator: cs.terminator})
+}
@@ -0,0 +1,142 @@
+package escapefilter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func Test_parseParams(t *testing.T) {
+	tests := []struct {
+		str     string
+		private string
+		params  [][]int
+	}{
+		{str: "", private: "", params: nil},
+		{str: "1;31", private: "", params: [][]int{{1}, {31}}},
+		{str: ";5", private: "", params: [][]int{{-1}, {5}}},
+		{str: "?25", private: "?", params: [][]int{{25}}},
+		{str: "?", private: "?", params: nil},
+		{str: "38:2::255:0:0", private: "", params: [][]int{{38, 2, -1, 255, 0, 0}}},
+		{str: "99999999999999999999", private: "", params: [][]int{{int(^uint(0) >> 1)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("str=%q", tt.str), func(t *testing.T) {
+			private, params := parseParams(tt.str)
+
+			if private != tt.private {
+				t.Errorf("private should be %q, got %q", tt.private, private)
+			}
+
+			if diff := cmp.Diff(tt.params, params); diff != "" {
+				t.Errorf("params differ from expected\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_Token_Param(t *testing.T) {
+	token := Token{Kind: TokenCSI, Params: [][]int{{-1}, {5}}, Final: "H"}
+
+	tests := []struct {
+		i        int
+		expected int
+	}{
+		{i: 0, expected: 1},
+		{i: 1, expected: 5},
+		{i: 2, expected: 1},
+		{i: -1, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("i=%d", tt.i), func(t *testing.T) {
+			if actual := token.Param(tt.i, 1); actual != tt.expected {
+				t.Errorf("Param() should return %d, got %d", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_Tokenizer(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b[01;32mあa\x1b[0m\r",
+		"\x1b]8;;https://example.com/\x1b\\link\x1b]8;;\x07",
+		"\x1b[1\x08;2H\x1bP1$qm\x1b\\\x1b_Gi=1\u009c\x1b(0q\x1b]0broken\x07\x1b[",
+	}, "\n")
+
+	expected := []Token{
+		{Kind: TokenCSI, Start: 0, End: 8, Params: [][]int{{1}, {32}}, Final: "m"},
+		{Kind: TokenText, Start: 8, End: 12, Text: "あa"},
+		{Kind: TokenCSI, Start: 12, End: 16, Params: [][]int{{0}}, Final: "m"},
+		{Kind: TokenControl, Start: 16, End: 17, Text: "\r"},
+		{Kind: TokenControl, Start: 17, End: 18, Text: "\n"},
+		{Kind: TokenOSC, Start: 18, End: 45, Command: "8", Data: ";https://example.com/", Terminator: "\x1b\\"},
+		{Kind: TokenText, Start: 45, End: 49, Text: "link"},
+		{Kind: TokenOSC, Start: 49, End: 55, Command: "8", Data: ";", Terminator: "\x07"},
+		{Kind: TokenControl, Start: 55, End: 56, Text: "\n"},
+		{Kind: TokenControl, Start: 59, End: 60, Text: "\x08"},
+		{Kind: TokenCSI, Start: 56, End: 63, Params: [][]int{{1}, {2}}, Final: "H"},
+		{Kind: TokenDCS, Start: 63, End: 71, Params: [][]int{{1}}, Intermediate: "$", Final: "q", Data: "m", Terminator: "\x1b\\"},
+		{Kind: TokenString, Start: 71, End: 79, Introducer: "_", Data: "Gi=1", Terminator: "\u009c"},
+		{Kind: TokenEscape, Start: 79, End: 82, Intermediate: "(", Final: "0"},
+		{Kind: TokenText, Start: 82, End: 83, Text: "q"},
+	}
+
+	tokenizer := NewTokenizer(strings.NewReader(source))
+
+	var actual []Token
+	for tokenizer.Scan() {
+		actual = append(actual, tokenizer.Token())
+	}
+
+	if err := tokenizer.Err(); err != nil {
+		t.Fatalf("Err() should return nil, got %v", err)
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("tokens differ from expected\n%s", diff)
+	}
+}
+
+func Test_Tokenizer_ISO8859_1(t *testing.T) {
+	tokenizer := NewTokenizer(strings.NewReader("\x1b%@caf\xe9\x9b1m"))
+
+	expected := []Token{
+		{Kind: TokenEscape, Start: 0, End: 3, Intermediate: "%", Final: "@"},
+		{Kind: TokenText, Start: 3, End: 7, Text: "café"},
+		{Kind: TokenCSI, Start: 7, End: 10, Params: [][]int{{1}}, Final: "m"},
+	}
+
+	var actual []Token
+	for tokenizer.Scan() {
+		actual = append(actual, tokenizer.Token())
+	}
+
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("tokens differ from expected\n%s", diff)
+	}
+}
+
+func Test_Tokenizer_Error(t *testing.T) {
+	errRead := errors.New("read error")
+	tokenizer := NewTokenizer(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(errRead)))
+
+	var actual []Token
+	for tokenizer.Scan() {
+		actual = append(actual, tokenizer.Token())
+	}
+
+	if err := tokenizer.Err(); err != errRead {
+		t.Errorf("Err() should return %v, got %v", errRead, err)
+	}
+
+	expected := []Token{{Kind: TokenText, Start: 0, End: 3, Text: "abc"}}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("tokens differ from expected\n%s", diff)
+	}
+}
@@ -0,0 +1,70 @@
+package escapefilter
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DirectoryChange represents a change of the working directory reported by OSC 7 (or OSC 633).
+type DirectoryChange struct {
+	Position
+
+	// Host is the host name (empty if not reported).
+	Host string `json:"host"`
+	// Path is the absolute path of the working directory.
+	Path string `json:"path"`
+}
+
+// parseWorkingDirectory parses the parameter of OSC 7, which is a file URI.
+// The path is percent-decoded.
+func parseWorkingDirectory(param string) (host string, path string, err error) {
+	u, err := url.Parse(param)
+	if err != nil {
+		return "", "", invalidOperatingSystemCommand
+	}
+
+	if u.Scheme != "file" || !strings.HasPrefix(u.Path, "/") {
+		return "", "", invalidOperatingSystemCommand
+	}
+
+	return u.Host, u.Path, nil
+}
+
+// parseVSCodeProperty parses the parameter of OSC 633 P, which is P ; <name>=<value>.
+func parseVSCodeProperty(param string) (name string, value string, ok bool) {
+	kind, prop, ok := strings.Cut(param, ";")
+	if !ok || kind != "P" {
+		return "", "", false
+	}
+
+	name, value, ok = strings.Cut(prop, "=")
+	if !ok {
+		return "", "", false
+	}
+
+	return name, unescape633(value), true
+}
+
+// changeDirectory records a change of the working directory.
+func (f *EscapeFilter) changeDirectory(host string, path string) {
+	f.addEvent(DirectoryChange{
+		Position: f.screen.Position(),
+		Host:     host,
+		Path:     path,
+	})
+}
+
+// WorkingDirectory returns the current working directory (empty if never reported).
+func (f *EscapeFilter) WorkingDirectory() string {
+	dirs := f.DirectoryHistory()
+	if len(dirs) == 0 {
+		return ""
+	}
+
+	return dirs[len(dirs)-1].Path
+}
+
+// DirectoryHistory returns all the changes of the working directory in order.
+func (f *EscapeFilter) DirectoryHistory() []DirectoryChange {
+	return eventsOf[DirectoryChange](f.events)
+}
@@ -0,0 +1,111 @@
+package escapefilter
+
+import (
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func Test_parseWorkingDirectory(t *testing.T) {
+	tests := []struct {
+		param   string
+		host    string
+		path    string
+		isError bool
+	}{
+		{param: "file://myhost/home/user", host: "myhost", path: "/home/user"},
+		{param: "file:///home/user", host: "", path: "/home/user"},
+		{param: "file://myhost/home/user/My%20Documents", host: "myhost", path: "/home/user/My Documents"},
+		{param: "file://myhost/tmp/%E3%81%82", host: "myhost", path: "/tmp/あ"},
+		{param: "http://myhost/home/user", isError: true},
+		{param: "file:home/user", isError: true},
+		{param: "file://myhost/home/%zz", isError: true},
+		{param: "/home/user", isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q", tt.param), func(t *testing.T) {
+			host, path, err := parseWorkingDirectory(tt.param)
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseWorkingDirectory() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseWorkingDirectory() should return not error, got %#v", err)
+			}
+
+			if host != tt.host {
+				t.Errorf("host should be %q, got %q", tt.host, host)
+			}
+
+			if path != tt.path {
+				t.Errorf("path should be %q, got %q", tt.path, path)
+			}
+		})
+	}
+}
+
+func Test_parseVSCodeProperty(t *testing.T) {
+	tests := []struct {
+		param string
+		name  string
+		value string
+		ok    bool
+	}{
+		{param: "P;Cwd=/home/user", name: "Cwd", value: "/home/user", ok: true},
+		{param: `P;Cwd=/home/a\x3bb`, name: "Cwd", value: "/home/a;b", ok: true},
+		{param: "P;IsWindows=False", name: "IsWindows", value: "False", ok: true},
+		{param: "P;Cwd", ok: false},
+		{param: "A", ok: false},
+		{param: "E;Cwd=/home/user", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("param=%q", tt.param), func(t *testing.T) {
+			name, value, ok := parseVSCodeProperty(tt.param)
+
+			if name != tt.name || value != tt.value || ok != tt.ok {
+				t.Errorf("parseVSCodeProperty() should return (%q, %q, %t), got (%q, %q, %t)", tt.name, tt.value, tt.ok, name, value, ok)
+			}
+		})
+	}
+}
+
+func Test_EscapeFilter_WorkingDirectory(t *testing.T) {
+	source := strings.Join([]string{
+		"\x1b]7;file://myhost/home/user\x07\x1b]133;A\x07$ \x1b]133;B\x07cd src\r",
+		"\x1b]133;C\x07\x1b]133;D;0\x07\x1b]7;file://myhost/home/user/src\x07\x1b]133;A\x07$ \x1b]133;B\x07ls\r",
+		"\x1b]133;C\x07main.go\r",
+		"\x1b]133;D;0\x07\x1b]7;http://invalid\x07\x1b]633;P;Cwd=/tmp\x07\x1b]133;A\x07$ \x1b]133;B\x07",
+	}, "\n")
+
+	filter := New()
+	filter.Load(strings.NewReader(source))
+
+	if dir := filter.WorkingDirectory(); dir != "/tmp" {
+		t.Errorf("WorkingDirectory() should return %q, got %q", "/tmp", dir)
+	}
+
+	expected := []DirectoryChange{
+		{Position: Position{Row: 1, Col: 1}, Host: "myhost", Path: "/home/user"},
+		{Position: Position{Row: 2, Col: 1}, Host: "myhost", Path: "/home/user/src"},
+		{Position: Position{Row: 4, Col: 1}, Host: "", Path: "/tmp"},
+	}
+
+	if diff := cmp.Diff(expected, filter.DirectoryHistory()); diff != "" {
+		t.Errorf("DirectoryHistory() differs from expected\n%s", diff)
+	}
+
+	commands := filter.Transcript()
+	if len(commands) != 2 {
+		t.Fatalf("Transcript() should return %d commands, got %d", 2, len(commands))
+	}
+
+	for i, dir := range []string{"/home/user", "/home/user/src"} {
+		if commands[i].Directory != dir {
+			t.Errorf("directory of command %d should be %q, got %q", i, dir, commands[i].Directory)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// followInterval is the interval to check whether the followed file has grown.
+const followInterval = 250 * time.Millisecond
+
+// follower reads a file as it grows, reopening it when it is truncated or rotated, like tail -F.
+type follower struct {
+	path string
+
+	// file is the file being read (nil if not opened yet).
+	file *os.File
+	// info is the information of the file being read, to detect rotation.
+	info os.FileInfo
+	// offset is the number of bytes read from the file.
+	offset int64
+}
+
+// open opens the file at the path, and reports whether it exists.
+func (fl *follower) open() (bool, error) {
+	file, err := os.Open(fl.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return false, err
+	}
+
+	fl.close()
+	fl.file = file
+	fl.info = info
+	fl.offset = 0
+
+	return true, nil
+}
+
+// close closes the file being read.
+func (fl *follower) close() {
+	if fl.file != nil {
+		fl.file.Close()
+		fl.file = nil
+	}
+}
+
+// read writes the contents of the file up to the current end to the Writer.
+func (fl *follower) read(w io.Writer) error {
+	if fl.file == nil {
+		return nil
+	}
+
+	n, err := io.Copy(w, fl.file)
+	fl.offset += n
+
+	return err
+}
+
+// check checks whether the file has been truncated or rotated, and reports whether the file should be read again.
+// A rotated file is read up to the end before switching to the new file, so that nothing written to it is lost.
+func (fl *follower) check(w io.Writer) (bool, error) {
+	if fl.file == nil {
+		return fl.open()
+	}
+
+	info, err := os.Stat(fl.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// removed or being rotated, wait for a new file
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(info, fl.info) {
+		if err := fl.read(w); err != nil {
+			return false, err
+		}
+
+		return fl.open()
+	}
+
+	if info.Size() < fl.offset {
+		fmt.Fprintf(os.Stderr, "%s: %s: file truncated\n", appname, fl.path)
+
+		if _, err := fl.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		fl.offset = 0
+
+		return true, nil
+	}
+
+	return info.Size() > fl.offset, nil
+}
+
+// follow writes the contents of the file to the Writer as the file grows, until a signal is received.
+// The file may not exist at first, and it is reopened when it is truncated or rotated.
+func follow(w io.Writer, path string, stop <-chan os.Signal) error {
+	fl := &follower{path: path}
+	defer fl.close()
+
+	for {
+		if err := fl.read(w); err != nil {
+			return err
+		}
+
+		again, err := fl.check(w)
+		if err != nil {
+			return err
+		}
+		if again {
+			continue
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(followInterval):
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_follower(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build.log")
+
+	var sb strings.Builder
+
+	fl := &follower{path: path}
+	defer fl.close()
+
+	// the file doesn't exist yet
+	if again, err := fl.check(&sb); again || err != nil {
+		t.Fatalf("check() should return (false, nil), got (%t, %v)", again, err)
+	}
+
+	old, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+
+	old.WriteString("1\n")
+
+	if again, err := fl.check(&sb); !again || err != nil {
+		t.Fatalf("check() should return (true, nil), got (%t, %v)", again, err)
+	}
+	if err := fl.read(&sb); err != nil {
+		t.Fatalf("read() should not return error, got %v", err)
+	}
+
+	// written to the old file after rotation
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	old.WriteString("2\n")
+
+	if err := os.WriteFile(path, []byte("3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if again, err := fl.check(&sb); !again || err != nil {
+		t.Fatalf("check() should return (true, nil), got (%t, %v)", again, err)
+	}
+	if err := fl.read(&sb); err != nil {
+		t.Fatalf("read() should not return error, got %v", err)
+	}
+
+	// truncated
+	if err := os.WriteFile(path, []byte("4"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if again, err := fl.check(&sb); !again || err != nil {
+		t.Fatalf("check() should return (true, nil), got (%t, %v)", again, err)
+	}
+	if err := fl.read(&sb); err != nil {
+		t.Fatalf("read() should not return error, got %v", err)
+	}
+
+	if actual := sb.String(); actual != "1\n2\n3\n4" {
+		t.Errorf("output should be %q, got %q", "1\n2\n3\n4", actual)
+	}
+}
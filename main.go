@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"github.com/blackwych/escapefilter/escapefilter"
 	"github.com/jessevdk/go-flags"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 )
 
 var (
@@ -23,9 +28,37 @@ type arguments struct {
 
 // options represents command-line options (and positional arguments)
 type options struct {
-	Help    bool      `short:"h" long:"help" description:"Print this help and exit"`
-	Version bool      `short:"v" long:"version" description:"Print version information and exit"`
-	Args    arguments `positional-args:"true"`
+	Format            string    `long:"format" choice:"text" choice:"json" choice:"html" choice:"markdown" default:"text" description:"Output format"`
+	ExpandDoubleWidth bool      `long:"expand-double-width" description:"Insert spaces between characters in double-width lines (text format only)"`
+	Links             string    `long:"links" choice:"plain" choice:"footnote" default:"plain" description:"How to output hyperlinks (text format only)"`
+	TitleMarkers      bool      `long:"title-markers" description:"Insert a line where the window title is changed (text format only)"`
+	NotifyMarkers     bool      `long:"notify-markers" description:"Insert a line where a desktop notification is sent (text format only)"`
+	Transcript        bool      `long:"transcript" description:"Output commands split by shell integration marks instead of the screen (text and json formats only)"`
+	DumpClipboard     string    `long:"dump-clipboard" value-name:"DIR" description:"Write each clipboard payload (OSC 52) to a file in DIR"`
+	ClipboardLimit    int       `long:"clipboard-limit" value-name:"BYTES" default:"1048576" description:"Discard clipboard payloads larger than BYTES"`
+	ExtractImages     string    `long:"extract-images" value-name:"DIR" description:"Write each inline image and file (OSC 1337 File=) to a file in DIR"`
+	ImageLimit        int       `long:"image-limit" value-name:"BYTES" default:"16777216" description:"Discard inline images larger than BYTES"`
+	Stream            bool      `long:"stream" description:"Output lines as soon as they become final (text format only)"`
+	Lookback          int       `long:"lookback" value-name:"ROWS" default:"24" description:"Number of rows which can still be changed in streaming mode"`
+	MaxRows           int       `long:"max-rows" value-name:"N" description:"Maximum number of rows in the screen, discarding rows from the top beyond it (0: unlimited)"`
+	MaxCols           int       `long:"max-cols" value-name:"N" default:"65536" description:"Maximum number of columns in a line (0: unlimited)"`
+	MaxCells          int       `long:"max-cells" value-name:"N" default:"67108864" description:"Maximum number of cells in the screen (0: unlimited)"`
+	MaxPayload        int       `long:"max-payload" value-name:"BYTES" default:"33554432" description:"Maximum length of parameters and strings of a sequence (0: unlimited)"`
+	StrictLimits      bool      `long:"strict-limits" description:"Exit with an error if the input exceeds a limit, instead of clamping"`
+	SharedScreen      bool      `long:"shared-screen" description:"Load all the input files into one screen, as in earlier versions"`
+	Headers           bool      `long:"headers" description:"Output a header like '==> INFILE <==' before the output of each input file"`
+	Recursive         bool      `short:"r" long:"recursive" description:"Read all the files under directories recursively"`
+	Include           []string  `long:"include" value-name:"GLOB" description:"Read only the files whose names match GLOB in directories (can be repeated)"`
+	Exclude           []string  `long:"exclude" value-name:"GLOB" description:"Skip the files whose names match GLOB in directories (can be repeated)"`
+	Jobs              int       `short:"j" long:"jobs" value-name:"N" default:"1" description:"Number of files processed in parallel"`
+	Follow            bool      `short:"f" long:"follow" description:"Keep reading the input file as it grows, like tail -F (implies --stream)"`
+	Output            string    `short:"o" long:"output" value-name:"FILE" description:"Write the output to FILE instead of the standard output"`
+	InPlace           *string   `long:"in-place" value-name:"SUFFIX" optional:"yes" optional-value:"" description:"Overwrite each input file with its output, keeping the original with SUFFIX if specified"`
+	OutputDir         string    `long:"output-dir" value-name:"DIR" description:"Write the output of each input file to a file in DIR with the same relative path"`
+	Compress          string    `long:"compress" choice:"auto" choice:"none" choice:"gzip" choice:"zlib" default:"auto" description:"Compress output files (auto: by the extension of the output file name)"`
+	Help              bool      `short:"h" long:"help" description:"Print this help and exit"`
+	Version           bool      `short:"v" long:"version" description:"Print version information and exit"`
+	Args              arguments `positional-args:"true"`
 }
 
 // versionInfo returns version information.
@@ -33,15 +66,15 @@ func versionInfo() string {
 	return fmt.Sprintf("%s %s", appname, version)
 }
 
-// parseOptions parses command-line options.
+// parseOptions parses command-line options (without the program name).
 // (nil, nil) means "Do nothing and exit successfully."
-func parseOptions() (*options, error) {
+func parseOptions(args []string) (*options, error) {
 	parser := flags.NewNamedParser(appname, flags.PassDoubleDash)
 
 	opts := &options{}
 	parser.AddGroup("Options", "Options", opts)
 
-	if _, err := parser.Parse(); err != nil {
+	if _, err := parser.ParseArgs(args); err != nil {
 		return nil, err
 	}
 
@@ -55,6 +88,75 @@ func parseOptions() (*options, error) {
 		return nil, nil
 	}
 
+	if opts.Transcript && opts.Format != "text" && opts.Format != "json" {
+		return nil, fmt.Errorf("--transcript is not available in %s format", opts.Format)
+	}
+
+	if opts.Follow {
+		if len(opts.Args.Infiles) != 1 || opts.Args.Infiles[0] == "-" {
+			return nil, fmt.Errorf("--follow requires exactly one input file")
+		}
+
+		opts.Stream = true
+	}
+
+	if opts.SharedScreen && opts.Headers {
+		return nil, fmt.Errorf("--headers is not available with --shared-screen")
+	}
+
+	if opts.Headers && opts.Format != "text" {
+		return nil, fmt.Errorf("--headers is not available in %s format", opts.Format)
+	}
+
+	destinations := 0
+	for _, specified := range []bool{opts.Output != "", opts.InPlace != nil, opts.OutputDir != ""} {
+		if specified {
+			destinations++
+		}
+	}
+
+	if destinations > 1 {
+		return nil, fmt.Errorf("--output, --in-place and --output-dir are exclusive")
+	}
+
+	if opts.Compress != "auto" && destinations == 0 {
+		return nil, fmt.Errorf("--compress requires --output, --in-place or --output-dir")
+	}
+
+	if opts.InPlace != nil || opts.OutputDir != "" {
+		if opts.SharedScreen || opts.Headers || opts.Follow {
+			return nil, fmt.Errorf("--in-place and --output-dir are not available with --shared-screen, --headers or --follow")
+		}
+
+		if len(opts.Args.Infiles) == 0 {
+			return nil, fmt.Errorf("--in-place and --output-dir require input files")
+		}
+
+		for _, infile := range opts.Args.Infiles {
+			if infile == "-" {
+				return nil, fmt.Errorf("--in-place and --output-dir are not available with the standard input")
+			}
+		}
+	}
+
+	if opts.Stream && (opts.Format != "text" || opts.Transcript) {
+		return nil, fmt.Errorf("--stream is available only in text format without --transcript")
+	}
+
+	if opts.Jobs <= 0 {
+		return nil, fmt.Errorf("--jobs must be positive, got %d", opts.Jobs)
+	}
+
+	for _, pattern := range append(opts.Include, opts.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+
+	if opts.Lookback <= 0 {
+		return nil, fmt.Errorf("--lookback must be positive, got %d", opts.Lookback)
+	}
+
 	// Use standard input if no files specified
 	if len(opts.Args.Infiles) == 0 {
 		opts.Args.Infiles = []string{"-"}
@@ -63,13 +165,79 @@ func parseOptions() (*options, error) {
 	return opts, nil
 }
 
+// checkInputs checks the options against the expanded input files.
+func checkInputs(opts *options, inputs []input) error {
+	// JSON and HTML documents can't be concatenated
+	if (opts.Format == "json" || opts.Format == "html") && len(inputs) > 1 &&
+		!opts.SharedScreen && opts.InPlace == nil && opts.OutputDir == "" {
+		return fmt.Errorf("multiple input files are not available in %s format without --shared-screen, --in-place or --output-dir", opts.Format)
+	}
+
+	return nil
+}
+
 // exitWithError reports error and exits with status 1 (= error).
 func exitWithError(err error) {
 	fmt.Fprintf(os.Stderr, "%s: %v\n", appname, err)
 	os.Exit(1)
 }
 
-// load loads an input file.
+// clipboardDumper writes clipboard payloads to numbered files in a directory.
+// It is safe for concurrent use by filters processing files in parallel.
+type clipboardDumper struct {
+	dir   string
+	count int
+	mu    sync.Mutex
+}
+
+// dump writes the clipboard payload to the next numbered file.
+func (d *clipboardDumper) dump(c escapefilter.Clipboard) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return err
+	}
+
+	d.count++
+	path := filepath.Join(d.dir, fmt.Sprintf("clipboard-%04d", d.count))
+
+	return os.WriteFile(path, c.Data, 0600)
+}
+
+// imageExtractor writes inline images to numbered files in a directory.
+// It is safe for concurrent use by filters processing files in parallel.
+type imageExtractor struct {
+	dir   string
+	count int
+	mu    sync.Mutex
+}
+
+// extract writes the inline file to the next numbered file, with the base name of the file if specified.
+// Inline images are named "image-N", and other files (downloads) are named "file-N".
+func (e *imageExtractor) extract(file escapefilter.InlineFile) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := os.MkdirAll(e.dir, 0755); err != nil {
+		return err
+	}
+
+	e.count++
+	kind := "file"
+	if file.Inline {
+		kind = "image"
+	}
+
+	name := fmt.Sprintf("%s-%04d", kind, e.count)
+	if base := filepath.Base(file.Name); file.Name != "" && base != "." && base != ".." && base != "/" {
+		name += "-" + base
+	}
+
+	return os.WriteFile(filepath.Join(e.dir, name), file.Data, 0600)
+}
+
+// load loads an input file, which is decompressed if compressed in gzip, bzip2 or zlib.
 func load(filter *escapefilter.EscapeFilter, filename string) error {
 	var file *os.File
 
@@ -85,15 +253,164 @@ func load(filter *escapefilter.EscapeFilter, filename string) error {
 		defer file.Close()
 	}
 
-	if err := filter.Load(file); err != nil {
+	rd, err := decompress(file)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	if err := filter.Load(rd); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// textOptions returns the options to render the screen in text format.
+func textOptions(opts *options) escapefilter.TextOptions {
+	textOpts := escapefilter.TextOptions{
+		ExpandDoubleWidth:   opts.ExpandDoubleWidth,
+		TitleMarkers:        opts.TitleMarkers,
+		NotificationMarkers: opts.NotifyMarkers,
+	}
+
+	if opts.Links == "footnote" {
+		textOpts.Links = escapefilter.LinkFootnote
+	}
+
+	return textOpts
+}
+
+// output writes the result of the filter to the Writer.
+func output(filter *escapefilter.EscapeFilter, opts *options, w io.Writer) error {
+	if opts.Transcript {
+		if opts.Format == "json" {
+			return filter.WriteTranscriptJSON(w)
+		}
+
+		return filter.WriteTranscriptText(w)
+	}
+
+	switch opts.Format {
+	case "json":
+		return filter.WriteJSON(w)
+	case "html":
+		return filter.WriteHTML(w)
+	case "markdown":
+		return filter.WriteMarkdown(w)
+	default:
+		_, err := io.WriteString(w, filter.Text(textOptions(opts)))
+		return err
+	}
+}
+
+// separatedWriter writes the outputs of input files, separating them by newlines.
+type separatedWriter struct {
+	w io.Writer
+
+	// written indicates whether anything has been written.
+	written bool
+	// last is the last byte written.
+	last byte
+}
+
+// Write writes the bytes to the underlying Writer.
+func (sw *separatedWriter) Write(b []byte) (int, error) {
+	if len(b) > 0 {
+		sw.written = true
+		sw.last = b[len(b)-1]
+	}
+
+	return sw.w.Write(b)
+}
+
+// begin ends the output of the previous file with a newline, and writes the header of the file if headers is true.
+// A blank line is inserted before a header following the previous output, like head and tail.
+func (sw *separatedWriter) begin(filename string, headers bool) error {
+	if sw.written && sw.last != '\n' {
+		if _, err := io.WriteString(sw, "\n"); err != nil {
+			return err
+		}
+	}
+
+	if !headers {
+		return nil
+	}
+
+	if sw.written {
+		if _, err := io.WriteString(sw, "\n"); err != nil {
+			return err
+		}
+	}
+
+	if filename == "-" {
+		filename = "standard input"
+	}
+
+	_, err := fmt.Fprintf(sw, "==> %s <==\n", filename)
+	return err
+}
+
+// newFilter returns a new EscapeFilter configured by the options.
+// The dumper and the extractor are shared by filters so that the files are numbered throughout the input files.
+func newFilter(opts *options, dumper *clipboardDumper, extractor *imageExtractor) *escapefilter.EscapeFilter {
+	filter := escapefilter.New()
+	filter.SetClipboardLimit(opts.ClipboardLimit)
+	filter.SetInlineFileLimit(opts.ImageLimit)
+	filter.SetLimits(escapefilter.Limits{
+		MaxRows:    opts.MaxRows,
+		MaxCols:    opts.MaxCols,
+		MaxCells:   opts.MaxCells,
+		MaxPayload: opts.MaxPayload,
+		Strict:     opts.StrictLimits,
+	})
+
+	if dumper != nil {
+		filter.SetClipboardHandler(dumper.dump)
+	}
+
+	if extractor != nil {
+		filter.SetInlineFileHandler(extractor.extract)
+	}
+
+	return filter
+}
+
+// process loads the input files into the filter, and writes the result to the Writer.
+func process(filter *escapefilter.EscapeFilter, opts *options, infiles []string, w io.Writer) error {
+	if opts.Stream {
+		filter.Stream(w, escapefilter.StreamOptions{
+			TextOptions: textOptions(opts),
+			Lookback:    opts.Lookback,
+		})
+	}
+
+	if opts.Follow {
+		// stop following by Ctrl-C and output the rest of the screen
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+		if err := follow(filter, infiles[0], stop); err != nil {
+			return err
+		}
+	} else {
+		for _, infile := range infiles {
+			if err := load(filter, infile); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.Stream {
+		// the rest of the screen is written by Close
+		return filter.Close()
+	}
+
+	return output(filter, opts, w)
+}
+
 func main() {
-	opts, err := parseOptions()
+	opts, err := parseOptions(os.Args[1:])
 	if err != nil {
 		exitWithError(err)
 		return
@@ -104,14 +421,78 @@ func main() {
 		return
 	}
 
-	filter := escapefilter.New()
+	var dumper *clipboardDumper
+	if opts.DumpClipboard != "" {
+		dumper = &clipboardDumper{dir: opts.DumpClipboard}
+	}
 
-	for _, infile := range opts.Args.Infiles {
-		if err := load(filter, infile); err != nil {
+	var extractor *imageExtractor
+	if opts.ExtractImages != "" {
+		extractor = &imageExtractor{dir: opts.ExtractImages}
+	}
+
+	// a followed file may not exist yet, so that it is not expanded
+	var inputs []input
+	if !opts.Follow {
+		inputs = expand(opts, opts.Args.Infiles)
+	}
+
+	if err := checkInputs(opts, inputs); err != nil {
+		exitWithError(err)
+		return
+	}
+
+	var out io.Writer = os.Stdout
+
+	var af *atomicFile
+	if opts.Output != "" {
+		af, err = createAtomic(opts.Output, "", opts.Compress)
+		if err != nil {
 			exitWithError(err)
 			return
 		}
+
+		out = af
+	}
+
+	ok := true
+
+	if opts.SharedScreen || opts.Follow {
+		infiles := opts.Args.Infiles
+		if !opts.Follow {
+			infiles = nil
+			for _, in := range inputs {
+				if in.err != nil {
+					reportError(in.path, in.err)
+					ok = false
+					continue
+				}
+
+				infiles = append(infiles, in.path)
+			}
+		}
+
+		filter := newFilter(opts, dumper, extractor)
+		if err := process(filter, opts, infiles, out); err != nil {
+			if af != nil {
+				af.abort()
+			}
+
+			exitWithError(err)
+			return
+		}
+	} else {
+		ok = processBatch(opts, inputs, out, dumper, extractor)
 	}
 
-	fmt.Print(filter)
+	if af != nil {
+		if err := af.commit(); err != nil {
+			exitWithError(err)
+			return
+		}
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
 }
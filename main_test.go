@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_parseOptions(t *testing.T) {
+	tests := []struct {
+		args    []string
+		isError bool
+	}{
+		{args: []string{"a.log", "b.log"}, isError: false},
+		{args: []string{"--headers", "a.log", "b.log"}, isError: false},
+		{args: []string{"--shared-screen", "a.log", "b.log"}, isError: false},
+		{args: []string{"--shared-screen", "--headers", "a.log", "b.log"}, isError: true},
+		{args: []string{"--headers", "--format", "json", "a.log"}, isError: true},
+		{args: []string{"--headers", "--format", "html", "a.log"}, isError: true},
+		{args: []string{"--headers", "--in-place", "a.log"}, isError: true},
+		{args: []string{"--shared-screen", "--output-dir", "out", "a.log"}, isError: true},
+		{args: []string{"--jobs", "0", "a.log"}, isError: true},
+		{args: []string{"--stream", "--format", "json", "a.log"}, isError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("args=%q", tt.args), func(t *testing.T) {
+			_, err := parseOptions(tt.args)
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("parseOptions() should return error")
+			case !tt.isError && isError:
+				t.Errorf("parseOptions() should not return error, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_checkInputs(t *testing.T) {
+	one := []input{{path: "a.log"}}
+	two := []input{{path: "a.log"}, {path: "b.log"}}
+	dir := "out"
+
+	tests := []struct {
+		opts    options
+		inputs  []input
+		isError bool
+	}{
+		{opts: options{Format: "text"}, inputs: two, isError: false},
+		{opts: options{Format: "markdown"}, inputs: two, isError: false},
+		{opts: options{Format: "json"}, inputs: one, isError: false},
+		{opts: options{Format: "json"}, inputs: two, isError: true},
+		{opts: options{Format: "html"}, inputs: two, isError: true},
+		{opts: options{Format: "json", SharedScreen: true}, inputs: two, isError: false},
+		{opts: options{Format: "html", InPlace: &dir}, inputs: two, isError: false},
+		{opts: options{Format: "json", OutputDir: dir}, inputs: two, isError: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("opts=%+v,inputs=%d", tt.opts, len(tt.inputs)), func(t *testing.T) {
+			err := checkInputs(&tt.opts, tt.inputs)
+
+			isError := err != nil
+			switch {
+			case tt.isError && !isError:
+				t.Errorf("checkInputs() should return error")
+			case !tt.isError && isError:
+				t.Errorf("checkInputs() should not return error, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_separatedWriter(t *testing.T) {
+	// file is an input file written to separatedWriter
+	type file struct {
+		name   string
+		output string
+	}
+
+	tests := []struct {
+		files    []file
+		headers  bool
+		expected string
+	}{
+		{
+			files:    []file{{"a.log", "a\n"}, {"b.log", "b\n"}},
+			headers:  false,
+			expected: "a\nb\n",
+		},
+		{
+			files:    []file{{"a.log", "a"}, {"b.log", "b"}},
+			headers:  false,
+			expected: "a\nb",
+		},
+		{
+			files:    []file{{"a.log", ""}, {"b.log", "b"}},
+			headers:  false,
+			expected: "b",
+		},
+		{
+			files:    []file{{"a.log", "a"}, {"-", "b\n"}},
+			headers:  true,
+			expected: "==> a.log <==\na\n\n==> standard input <==\nb\n",
+		},
+		{
+			files:    []file{{"a.log", ""}, {"b.log", "b"}},
+			headers:  true,
+			expected: "==> a.log <==\n\n==> b.log <==\nb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("files=%q,headers=%t", tt.files, tt.headers), func(t *testing.T) {
+			var buf bytes.Buffer
+			sw := &separatedWriter{w: &buf}
+
+			for _, f := range tt.files {
+				if err := sw.begin(f.name, tt.headers); err != nil {
+					t.Fatalf("begin() should not return error, got %v", err)
+				}
+
+				io.WriteString(sw, f.output)
+			}
+
+			if actual := buf.String(); actual != tt.expected {
+				t.Errorf("separatedWriter should write %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_process_DefaultLimits(t *testing.T) {
+	tests := []string{
+		"\x1b[999999999;1HX",
+		"\x1b[999999999BX",
+		"abc\x1b[999999999;999999999HX",
+	}
+
+	opts, err := parseOptions(nil)
+	if err != nil {
+		t.Fatalf("parseOptions() should not return error, got %v", err)
+	}
+
+	for _, source := range tests {
+		t.Run(fmt.Sprintf("source=%q", source), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hostile.log")
+			if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := process(newFilter(opts, nil, nil), opts, []string{path}, &buf); err != nil {
+				t.Fatalf("process() should not return error, got %v", err)
+			}
+
+			// each row is counted as 16 cells at least
+			if rows := strings.Count(buf.String(), "\n") + 1; rows > opts.MaxCells/16 {
+				t.Errorf("process() should write at most %d rows, got %d", opts.MaxCells/16, rows)
+			}
+		})
+	}
+}
+
+func Test_process_SharedScreen(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	if err := os.WriteFile(a, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("\rX"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &options{Format: "text", Links: "plain", Jobs: 1}
+
+	// the files are loaded into one screen with --shared-screen, or each on its own screen otherwise
+	var shared bytes.Buffer
+	if err := process(newFilter(opts, nil, nil), opts, []string{a, b}, &shared); err != nil {
+		t.Fatalf("process() should not return error, got %v", err)
+	}
+
+	if expected, actual := "Xbc", shared.String(); actual != expected {
+		t.Errorf("process() should write %q, got %q", expected, actual)
+	}
+
+	var separated bytes.Buffer
+	if ok := processBatch(opts, []input{{path: a}, {path: b}}, &separated, nil, nil); !ok {
+		t.Fatalf("processBatch() should return true")
+	}
+
+	if expected, actual := "abc\nX", separated.String(); actual != expected {
+		t.Errorf("processBatch() should write %q, got %q", expected, actual)
+	}
+}
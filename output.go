@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultOutputMode is the permission of a new output file.
+const defaultOutputMode fs.FileMode = 0644
+
+// atomicFile is an output file written atomically, by writing a temporary file and renaming it.
+type atomicFile struct {
+	// file is the temporary file.
+	file *os.File
+
+	// path is the path of the output file.
+	path string
+	// mode is the permission of the output file, which is preserved if the file exists.
+	mode fs.FileMode
+	// backup is the path to which the existing file is moved ("" if not backed up).
+	backup string
+	// zw compresses the output (nil if not compressed).
+	zw io.WriteCloser
+}
+
+// createAtomic creates a temporary file in the same directory as the output file,
+// which is compressed according to the format (see compression).
+// The backup is made only if the output file exists.
+func createAtomic(path string, backup string, format string) (*atomicFile, error) {
+	format, err := compression(format, path)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := defaultOutputMode
+
+	info, err := os.Stat(path)
+	if err == nil {
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s: not a regular file", path)
+		}
+
+		mode = info.Mode().Perm()
+	} else if errors.Is(err, fs.ErrNotExist) {
+		backup = ""
+	} else {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	af := &atomicFile{file: file, path: path, mode: mode, backup: backup}
+	af.zw = compressor(file, format)
+
+	return af, nil
+}
+
+// Write writes the bytes to the temporary file, compressing them if needed.
+func (af *atomicFile) Write(b []byte) (int, error) {
+	if af.zw != nil {
+		return af.zw.Write(b)
+	}
+
+	return af.file.Write(b)
+}
+
+// commit replaces the output file with the temporary file, keeping the existing file as the backup if specified.
+// The output file always exists during commit, since it is replaced by a single rename.
+func (af *atomicFile) commit() error {
+	if af.zw != nil {
+		if err := af.zw.Close(); err != nil {
+			af.abort()
+			return err
+		}
+	}
+
+	if err := af.file.Chmod(af.mode); err != nil {
+		af.abort()
+		return err
+	}
+
+	if err := af.file.Sync(); err != nil {
+		af.abort()
+		return err
+	}
+
+	if err := af.file.Close(); err != nil {
+		os.Remove(af.file.Name())
+		return err
+	}
+
+	if af.backup != "" {
+		if err := makeBackup(af.path, af.backup); err != nil {
+			os.Remove(af.file.Name())
+			return err
+		}
+	}
+
+	return os.Rename(af.file.Name(), af.path)
+}
+
+// makeBackup makes the backup of the file by a hard link, or by a copy if links are not supported.
+func makeBackup(path string, backup string) error {
+	if err := os.Remove(backup); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Link(path, backup); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
+
+// abort removes the temporary file, leaving the output file as it is.
+func (af *atomicFile) abort() {
+	af.file.Close()
+	os.Remove(af.file.Name())
+}
+
+// destination returns the path of the output file for the input file, with --in-place or --output-dir.
+// In --output-dir, the relative path of the input file is preserved if it is within the current directory,
+// and the path from the base name of the directory given as an argument (or of the file itself) otherwise.
+func destination(opts *options, in input) string {
+	if opts.OutputDir == "" {
+		return in.path
+	}
+
+	return filepath.Join(opts.OutputDir, in.rel)
+}
+
+// processTo processes the input file and writes the result to the output file atomically.
+func processTo(opts *options, infile string, path string, dumper *clipboardDumper, extractor *imageExtractor) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	backup := ""
+	if opts.InPlace != nil && *opts.InPlace != "" {
+		backup = path + *opts.InPlace
+	}
+
+	af, err := createAtomic(path, backup, opts.Compress)
+	if err != nil {
+		return err
+	}
+
+	filter := newFilter(opts, dumper, extractor)
+	if err := process(filter, opts, []string{infile}, af); err != nil {
+		af.abort()
+		return err
+	}
+
+	return af.commit()
+}
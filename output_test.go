@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_destination(t *testing.T) {
+	tests := []struct {
+		outputDir string
+		in        input
+		expected  string
+	}{
+		{in: input{path: "logs/build.log", rel: "logs/build.log"}, expected: "logs/build.log"},
+		{outputDir: "out", in: input{path: "logs/build.log", rel: "logs/build.log"}, expected: "out/logs/build.log"},
+		{outputDir: "out", in: input{path: "/var/log/build.log", rel: "build.log"}, expected: "out/build.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("outputDir=%q,in=%+v", tt.outputDir, tt.in), func(t *testing.T) {
+			opts := &options{OutputDir: tt.outputDir}
+
+			if actual := destination(opts, tt.in); actual != filepath.FromSlash(tt.expected) {
+				t.Errorf("destination() should return %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_atomicFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build.log")
+
+	if err := os.WriteFile(path, []byte("original"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	// aborted
+	af, err := createAtomic(path, path+".orig", "none")
+	if err != nil {
+		t.Fatalf("createAtomic() should not return error, got %v", err)
+	}
+	af.Write([]byte("aborted"))
+	af.abort()
+
+	if data, _ := os.ReadFile(path); string(data) != "original" {
+		t.Errorf("file should be %q after abort, got %q", "original", data)
+	}
+
+	// committed
+	af, err = createAtomic(path, path+".orig", "none")
+	if err != nil {
+		t.Fatalf("createAtomic() should not return error, got %v", err)
+	}
+	af.Write([]byte("replaced"))
+
+	if err := af.commit(); err != nil {
+		t.Fatalf("commit() should not return error, got %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "replaced" {
+		t.Errorf("file should be %q after commit, got %q", "replaced", data)
+	}
+
+	if data, _ := os.ReadFile(path + ".orig"); string(data) != "original" {
+		t.Errorf("backup should be %q, got %q", "original", data)
+	}
+
+	if info, err := os.Stat(path); err != nil {
+		t.Errorf("Stat() should not return error, got %v", err)
+	} else if info.Mode().Perm() != 0640 {
+		t.Errorf("permission should be preserved as %v, got %v", os.FileMode(0640), info.Mode().Perm())
+	}
+
+	// no temporary files are left
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("directory should have 2 files, got %d", len(entries))
+	}
+}